@@ -3,6 +3,8 @@ module github.com/stolostron/managed-serviceaccount-e2e
 go 1.21
 
 require (
+	github.com/evanphx/json-patch/v5 v5.6.0
+	github.com/ghodss/yaml v1.0.0
 	github.com/onsi/ginkgo/v2 v2.15.0
 	github.com/onsi/gomega v1.30.0
 	github.com/stolostron/library-e2e-go v0.0.0-20230104093627-3d6e66f9cdd8
@@ -20,8 +22,6 @@ require (
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/emicklei/go-restful/v3 v3.9.0 // indirect
-	github.com/evanphx/json-patch/v5 v5.6.0 // indirect
-	github.com/ghodss/yaml v1.0.0 // indirect
 	github.com/go-logr/logr v1.3.0 // indirect
 	github.com/go-openapi/jsonpointer v0.19.6 // indirect
 	github.com/go-openapi/jsonreference v0.20.1 // indirect
@@ -2,6 +2,7 @@ package clients
 
 import (
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 
 	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
 	libgoclient "github.com/stolostron/library-go/pkg/client"
@@ -20,24 +21,27 @@ func GetHubDynamicClient() (dynamic.Interface, error) {
 	return dynamicClient, nil
 }
 
-func GetManagedClusterDynamicClient(managedClusterName string) (dynamic.Interface, error) {
-	var targetManagedCluster libgooptions.Cluster
-	optionManagedClusters := libgooptions.TestOptions.Options.ManagedClusters
-	for _, cluster := range optionManagedClusters {
-		if cluster.Name == managedClusterName {
-			targetManagedCluster = cluster
-			break
-		}
+// GetHubKubeClient returns a typed client for the hub, mostly used for its discovery client
+func GetHubKubeClient() (kubernetes.Interface, error) {
+	kubeClient, err := libgoclient.NewKubeClient(
+		libgooptions.TestOptions.Options.Hub.ApiServerURL,
+		libgooptions.TestOptions.Options.Hub.KubeConfig,
+		libgooptions.TestOptions.Options.Hub.KubeContext,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	dynamicClient, err := libgoclient.NewKubeClientDynamic(
-		"",
-		targetManagedCluster.KubeConfig,
-		targetManagedCluster.KubeContext,
-	)
+	return kubeClient, nil
+}
+
+// GetManagedClusterDynamicClient returns a client for a cluster of the options, with the credentials
+// of NewClusterRegistryFromOptions. an *UnknownClusterError is returned for a cluster the options do not list
+func GetManagedClusterDynamicClient(managedClusterName string) (dynamic.Interface, error) {
+	registry, err := NewClusterRegistryFromOptions()
 	if err != nil {
 		return nil, err
 	}
 
-	return dynamicClient, nil
+	return registry.DynamicClient(managedClusterName)
 }
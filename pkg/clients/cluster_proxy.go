@@ -0,0 +1,119 @@
+package clients
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ClusterProxyEndpoint is how the cluster-proxy user server is reached, the managed cluster
+// apiserver is served under URL/<cluster name>
+type ClusterProxyEndpoint struct {
+	URL    string
+	CAData []byte
+	// Insecure skips the verification of the user server certificate
+	Insecure bool
+}
+
+// GetClusterProxyEndpoint completes the endpoint from the hub, an empty url is read from the
+// cluster-proxy-addon-user Route, and without a caFile the ingress CA of the hub is trusted
+func GetClusterProxyEndpoint(
+	hubClient dynamic.Interface,
+	url string,
+	caFile string,
+	insecure bool,
+) (*ClusterProxyEndpoint, error) {
+	endpoint := &ClusterProxyEndpoint{
+		URL:      strings.TrimSuffix(url, "/"),
+		Insecure: insecure,
+	}
+
+	if endpoint.URL == "" {
+		var err error
+		endpoint.URL, err = utils.GetClusterProxyUserServerURL(hubClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case insecure:
+	case caFile != "":
+		caData, err := os.ReadFile(filepath.Clean(caFile))
+		if err != nil {
+			return nil, err
+		}
+		endpoint.CAData = caData
+	default:
+		caData, err := utils.GetIngressCA(hubClient)
+		if err != nil {
+			return nil, fmt.Errorf("fail to find the CA of the cluster-proxy user server, set a caFile in the options: %v", err)
+		}
+		endpoint.CAData = caData
+	}
+
+	return endpoint, nil
+}
+
+// GetClusterProxyRestConfig builds a rest.Config that reaches the managed cluster apiserver through the
+// cluster-proxy user server and authenticates with the token of the ManagedServiceAccount. a
+// *utils.ClusterProxyNotInstalledError is returned when cluster-proxy is not available for the cluster
+func GetClusterProxyRestConfig(
+	hubClient dynamic.Interface,
+	endpoint *ClusterProxyEndpoint,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (*rest.Config, error) {
+	if err := utils.CheckClusterProxyInstalled(hubClient, managedCluster); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rest.Config{
+		Host:        endpoint.URL + "/" + managedCluster.Name,
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:   endpoint.CAData,
+			Insecure: endpoint.Insecure,
+		},
+	}, nil
+}
+
+func GetClusterProxyDynamicClient(
+	hubClient dynamic.Interface,
+	endpoint *ClusterProxyEndpoint,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (dynamic.Interface, error) {
+	config, err := GetClusterProxyRestConfig(hubClient, endpoint, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(config)
+}
+
+func GetClusterProxyKubeClient(
+	hubClient dynamic.Interface,
+	endpoint *ClusterProxyEndpoint,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (kubernetes.Interface, error) {
+	config, err := GetClusterProxyRestConfig(hubClient, endpoint, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(config)
+}
@@ -0,0 +1,287 @@
+package clients
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	CredentialTypeKubeconfig = "kubeconfig"
+	CredentialTypeToken      = "token"
+	CredentialTypeInCluster  = "in-cluster"
+	CredentialTypeExec       = "exec"
+)
+
+// ClusterProbeTimeout bounds the request made to check a cluster is reachable
+const ClusterProbeTimeout = 30 * time.Second
+
+// UnknownClusterError is returned for a cluster name the options have no credentials for
+type UnknownClusterError struct {
+	Name  string
+	Known []string
+}
+
+func (e *UnknownClusterError) Error() string {
+	return fmt.Sprintf("no credentials for cluster %q in the options, known clusters: [%s]",
+		e.Name, strings.Join(e.Known, ", "))
+}
+
+// ClusterRegistry holds the credentials of every managed cluster of the options. unlike
+// libgoclient it never falls back to $KUBECONFIG, the in-cluster config or ~/.kube/config
+type ClusterRegistry struct {
+	credentials map[string]options.ClusterCredentialOptions
+}
+
+// NewClusterRegistry registers the credentials, a name may only be given once. the credentials of a
+// cluster are validated when it is used, so a cluster missing from the options is reported as unknown
+// whatever the state of the other entries
+func NewClusterRegistry(credentials []options.ClusterCredentialOptions) (*ClusterRegistry, error) {
+	registry := &ClusterRegistry{credentials: map[string]options.ClusterCredentialOptions{}}
+
+	errs := []error{}
+	for _, credential := range credentials {
+		if credential.Name == "" {
+			errs = append(errs, fmt.Errorf("cluster credentials without a name"))
+			continue
+		}
+		if _, ok := registry.credentials[credential.Name]; ok {
+			errs = append(errs, fmt.Errorf("cluster %s: credentials given twice", credential.Name))
+			continue
+		}
+		registry.credentials[credential.Name] = credential
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// NewClusterRegistryFromOptions registers the clusters of the library-e2e-go options with their
+// kubeconfig, replaced by the clusterCredentials entry of the same name when there is one
+func NewClusterRegistryFromOptions() (*ClusterRegistry, error) {
+	credentials := []options.ClusterCredentialOptions{}
+	index := map[string]int{}
+	for _, cluster := range libgooptions.TestOptions.Options.ManagedClusters {
+		index[cluster.Name] = len(credentials)
+		credentials = append(credentials, options.ClusterCredentialOptions{
+			Name:         cluster.Name,
+			Kubeconfig:   cluster.KubeConfig,
+			KubeContext:  cluster.KubeContext,
+			APIServerURL: cluster.ApiServerURL,
+		})
+	}
+
+	for _, credential := range options.TestOptions.Options.ManagedServiceAccount.ClusterCredentials {
+		i, ok := index[credential.Name]
+		if !ok {
+			return nil, fmt.Errorf("clusterCredentials entry %q is not one of the clusters of the options", credential.Name)
+		}
+		credentials[i] = credential
+	}
+
+	return NewClusterRegistry(credentials)
+}
+
+// Names returns the registered clusters, sorted
+func (r *ClusterRegistry) Names() []string {
+	names := []string{}
+	for name := range r.credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Type returns which kind of credentials the cluster is reached with, an error unless exactly one kind is set
+func (r *ClusterRegistry) Type(name string) (string, error) {
+	credential, ok := r.credentials[name]
+	if !ok {
+		return "", &UnknownClusterError{Name: name, Known: r.Names()}
+	}
+
+	credentialType, err := credentialType(credential)
+	if err != nil {
+		return "", fmt.Errorf("cluster %s: %v", name, err)
+	}
+	return credentialType, nil
+}
+
+// RestConfig builds the rest.Config of the cluster, an *UnknownClusterError is returned for unknown names
+func (r *ClusterRegistry) RestConfig(name string) (*rest.Config, error) {
+	credential, ok := r.credentials[name]
+	if !ok {
+		return nil, &UnknownClusterError{Name: name, Known: r.Names()}
+	}
+
+	config, err := credentialRestConfig(credential)
+	if err != nil {
+		return nil, fmt.Errorf("cluster %s: %v", name, err)
+	}
+	return config, nil
+}
+
+func (r *ClusterRegistry) DynamicClient(name string) (dynamic.Interface, error) {
+	config, err := r.RestConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(config)
+}
+
+// Probe checks the cluster is reachable with its credentials by reading its version
+func (r *ClusterRegistry) Probe(name string) error {
+	config, err := r.RestConfig(name)
+	if err != nil {
+		return err
+	}
+
+	config.Timeout = ClusterProbeTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return fmt.Errorf("cluster %s: %v", name, err)
+	}
+	if _, err := discoveryClient.ServerVersion(); err != nil {
+		credentialType, _ := r.Type(name)
+		return fmt.Errorf("cluster %s is not reachable at %s with %s credentials: %v", name, config.Host, credentialType, err)
+	}
+	return nil
+}
+
+// ProbeAll probes every cluster and returns one error listing all the invalid or unreachable ones
+func (r *ClusterRegistry) ProbeAll() error {
+	errs := []error{}
+	for _, name := range r.Names() {
+		if err := r.Probe(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// credentialType returns the kind of the credentials, an error unless exactly one kind is set
+func credentialType(credential options.ClusterCredentialOptions) (string, error) {
+	types := []string{}
+	if credential.Kubeconfig != "" {
+		types = append(types, CredentialTypeKubeconfig)
+	}
+	if credential.Token != "" || credential.TokenFile != "" {
+		types = append(types, CredentialTypeToken)
+	}
+	if credential.InCluster {
+		types = append(types, CredentialTypeInCluster)
+	}
+	if credential.Exec != nil {
+		types = append(types, CredentialTypeExec)
+	}
+
+	switch {
+	case len(types) == 0:
+		return "", fmt.Errorf("no credentials, set a kubeconfig, a token, inCluster or an exec plugin")
+	case len(types) > 1:
+		return "", fmt.Errorf("only one kind of credentials may be set, got %s", strings.Join(types, " and "))
+	}
+
+	switch types[0] {
+	case CredentialTypeToken:
+		if credential.Token != "" && credential.TokenFile != "" {
+			return "", fmt.Errorf("set either token or tokenFile")
+		}
+		if credential.APIServerURL == "" {
+			return "", fmt.Errorf("a token needs the apiServerURL")
+		}
+	case CredentialTypeExec:
+		if credential.Exec.Command == "" {
+			return "", fmt.Errorf("the exec plugin has no command")
+		}
+		if credential.APIServerURL == "" {
+			return "", fmt.Errorf("an exec plugin needs the apiServerURL")
+		}
+	case CredentialTypeInCluster:
+		if credential.APIServerURL != "" || credential.CAFile != "" || credential.KubeContext != "" {
+			return "", fmt.Errorf("inCluster takes no apiServerURL, caFile or kubecontext")
+		}
+	}
+	if credential.CAFile != "" && credential.Insecure {
+		return "", fmt.Errorf("set either caFile or insecure")
+	}
+	if credential.KubeContext != "" && types[0] != CredentialTypeKubeconfig {
+		return "", fmt.Errorf("kubecontext is only used with a kubeconfig")
+	}
+	return types[0], nil
+}
+
+func credentialRestConfig(credential options.ClusterCredentialOptions) (*rest.Config, error) {
+	credentialType, err := credentialType(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	switch credentialType {
+	case CredentialTypeKubeconfig:
+		// an explicit path only, no merging with $KUBECONFIG or ~/.kube/config
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: credential.KubeContext}
+		overrides.ClusterInfo.Server = credential.APIServerURL
+		overrides.ClusterInfo.CertificateAuthority = credential.CAFile
+		overrides.ClusterInfo.InsecureSkipTLSVerify = credential.Insecure
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: filepath.Clean(credential.Kubeconfig)},
+			overrides,
+		).ClientConfig()
+	case CredentialTypeInCluster:
+		return rest.InClusterConfig()
+	}
+
+	config := &rest.Config{
+		Host: credential.APIServerURL,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: credential.Insecure,
+		},
+	}
+	if credential.CAFile != "" {
+		caData, err := os.ReadFile(filepath.Clean(credential.CAFile))
+		if err != nil {
+			return nil, err
+		}
+		config.TLSClientConfig.CAData = caData
+	}
+
+	if credentialType == CredentialTypeToken {
+		config.BearerToken = credential.Token
+		config.BearerTokenFile = credential.TokenFile
+		return config, nil
+	}
+
+	exec := credential.Exec
+	apiVersion := exec.APIVersion
+	if apiVersion == "" {
+		apiVersion = "client.authentication.k8s.io/v1"
+	}
+	env := []clientcmdapi.ExecEnvVar{}
+	for name, value := range exec.Env {
+		env = append(env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	config.ExecProvider = &clientcmdapi.ExecConfig{
+		Command:    exec.Command,
+		Args:       exec.Args,
+		Env:        env,
+		APIVersion: apiVersion,
+		// the suite has no terminal to prompt on
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+	return config, nil
+}
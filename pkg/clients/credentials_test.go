@@ -0,0 +1,82 @@
+package clients
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+)
+
+func TestClusterRegistryRefusesAmbiguousOrIncompleteCredentials(t *testing.T) {
+	registry, err := NewClusterRegistry([]options.ClusterCredentialOptions{
+		{Name: "both", Kubeconfig: "/tmp/kubeconfig", Token: "token", APIServerURL: "https://api.invalid:6443"},
+		{Name: "token-without-url", Token: "token"},
+		{Name: "exec-without-command", APIServerURL: "https://api.invalid:6443", Exec: &options.ExecCredentialOptions{}},
+		{Name: "none"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"both":                 "cluster both: only one kind of credentials",
+		"token-without-url":    "cluster token-without-url: a token needs the apiServerURL",
+		"exec-without-command": "cluster exec-without-command: the exec plugin has no command",
+		"none":                 "cluster none: no credentials",
+	}
+	for name, message := range expected {
+		if _, err := registry.RestConfig(name); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %q for cluster %s, got %v", message, name, err)
+		}
+	}
+
+	// the credentials are checked before any cluster is dialed
+	err = registry.ProbeAll()
+	if err == nil {
+		t.Fatal("expected every cluster to be refused")
+	}
+	for _, message := range expected {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %q in %v", message, err)
+		}
+	}
+}
+
+func TestClusterRegistryRefusesDuplicateOrUnnamedCredentials(t *testing.T) {
+	_, err := NewClusterRegistry([]options.ClusterCredentialOptions{
+		{Name: "twice", InCluster: true},
+		{Name: "twice", InCluster: true},
+		{Kubeconfig: "/tmp/kubeconfig"},
+	})
+	if err == nil {
+		t.Fatal("expected duplicate and unnamed credentials to be refused")
+	}
+	for _, message := range []string{"cluster twice: credentials given twice", "cluster credentials without a name"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("expected %q in %v", message, err)
+		}
+	}
+}
+
+func TestClusterRegistryReportsUnknownClusterWithIncompleteEntries(t *testing.T) {
+	previous := libgooptions.TestOptions
+	defer func() { libgooptions.TestOptions = previous }()
+
+	// a hub-only options file lists the clusters by name only
+	libgooptions.TestOptions.Options.ManagedClusters = []libgooptions.Cluster{{Name: "cluster1"}}
+
+	_, err := GetManagedClusterDynamicClient("e2e-not-in-options")
+	var unknownErr *UnknownClusterError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("expected an *UnknownClusterError, got %v", err)
+	}
+	if unknownErr.Name != "e2e-not-in-options" || len(unknownErr.Known) != 1 || unknownErr.Known[0] != "cluster1" {
+		t.Errorf("unexpected error %#v", unknownErr)
+	}
+
+	if _, err := GetManagedClusterDynamicClient("cluster1"); err == nil || !strings.Contains(err.Error(), "cluster cluster1: no credentials") {
+		t.Errorf("expected cluster1 to be refused for its missing credentials, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// GetManagedServiceAccountRestConfig builds a rest.Config for the managed cluster apiserver that
+// authenticates with the token and trusts only the ca.crt of the ManagedServiceAccount secret,
+// the way consumers of ManagedServiceAccount tokens reach the managed cluster
+func GetManagedServiceAccountRestConfig(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (*rest.Config, error) {
+	if len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 ||
+		managedCluster.Spec.ManagedClusterClientConfigs[0].URL == "" {
+		return nil, fmt.Errorf("managed cluster %s has no managedClusterClientConfigs url", managedCluster.Name)
+	}
+
+	secret, err := utils.GetManagedServiceAccountSecret(hubClient, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secret.Data["token"]) == 0 {
+		return nil, fmt.Errorf("empty token in secret %s/%s", secret.Namespace, secret.Name)
+	}
+	if len(secret.Data["ca.crt"]) == 0 {
+		return nil, fmt.Errorf("empty ca.crt in secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	return &rest.Config{
+		Host:        managedCluster.Spec.ManagedClusterClientConfigs[0].URL,
+		BearerToken: string(secret.Data["token"]),
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: secret.Data["ca.crt"],
+		},
+	}, nil
+}
+
+// GetManagedServiceAccountRestConfigWithClusterCABundle is GetManagedServiceAccountRestConfig trusting the
+// caBundle of the managedClusterClientConfigs too, the ca.crt signs the in-cluster serving certificate
+// while the url may be served with another one
+func GetManagedServiceAccountRestConfigWithClusterCABundle(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (*rest.Config, error) {
+	config, err := GetManagedServiceAccountRestConfig(hubClient, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	if caBundle := managedCluster.Spec.ManagedClusterClientConfigs[0].CABundle; len(caBundle) > 0 {
+		caData := append([]byte{}, config.CAData...)
+		config.CAData = append(append(caData, '\n'), caBundle...)
+	}
+	return config, nil
+}
+
+func GetManagedServiceAccountDynamicClient(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (dynamic.Interface, error) {
+	config, err := GetManagedServiceAccountRestConfig(hubClient, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(config)
+}
+
+func GetManagedServiceAccountKubeClient(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (kubernetes.Interface, error) {
+	config, err := GetManagedServiceAccountRestConfig(hubClient, managedCluster, managedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// GetManagedClusterDynamicClientFromHub reaches the managed cluster with hub credentials only, through the
+// cluster-admin bootstrap ManagedServiceAccount set up by utils.BootstrapManagedClusterAccess, trusting the
+// caBundle of the managedClusterClientConfigs too
+func GetManagedClusterDynamicClientFromHub(
+	ctx context.Context,
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) (dynamic.Interface, error) {
+	if err := utils.BootstrapManagedClusterAccess(ctx, hubClient, managedCluster); err != nil {
+		return nil, err
+	}
+
+	config, err := GetManagedServiceAccountRestConfigWithClusterCABundle(hubClient, managedCluster, utils.BootstrapManagedServiceAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(config)
+}
@@ -0,0 +1,354 @@
+package options
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+)
+
+const (
+	// EnvOptions, EnvKubeconfig and EnvImportKubeconfig are set by the Dockerfile
+	EnvOptions            = "OPTIONS"
+	EnvKubeconfig         = "KUBECONFIG"
+	EnvImportKubeconfig   = "IMPORT_KUBECONFIG"
+	EnvManagedClusterName = "MANAGED_CLUSTER_NAME"
+
+	DefaultOptionsFile = "resources/options.yaml"
+	// DefaultManagedClusterName names the managed cluster added when the options have none
+	DefaultManagedClusterName = "cluster1"
+)
+
+// optionOverride sets one option from an environment variable, then from a flag
+type optionOverride struct {
+	// setting is the path of the option in the options file
+	setting string
+	env     string
+	flag    string
+	usage   string
+	get     func(o *libgooptions.TestOptionsT) string
+	set     func(o *libgooptions.TestOptionsT, value string, sources map[string]string)
+}
+
+var optionOverrides = []optionOverride{
+	{
+		setting: "hub.kubeconfig",
+		env:     EnvKubeconfig,
+		flag:    "hub-kubeconfig",
+		usage:   "kubeconfig of the hub",
+		get: func(o *libgooptions.TestOptionsT) string {
+			return o.Hub.KubeConfig
+		},
+		set: func(o *libgooptions.TestOptionsT, value string, _ map[string]string) {
+			o.Hub.KubeConfig = value
+		},
+	},
+	{
+		setting: "hub.kubecontext",
+		flag:    "hub-kubecontext",
+		usage:   "context of the hub kubeconfig",
+		get: func(o *libgooptions.TestOptionsT) string {
+			return o.Hub.KubeContext
+		},
+		set: func(o *libgooptions.TestOptionsT, value string, _ map[string]string) {
+			o.Hub.KubeContext = value
+		},
+	},
+	{
+		setting: "clusters[0].name",
+		env:     EnvManagedClusterName,
+		flag:    "managed-cluster-name",
+		usage:   "name of the first managed cluster of the options",
+		get: func(o *libgooptions.TestOptionsT) string {
+			if len(o.ManagedClusters) == 0 {
+				return ""
+			}
+			return o.ManagedClusters[0].Name
+		},
+		set: func(o *libgooptions.TestOptionsT, value string, sources map[string]string) {
+			firstManagedCluster(o, sources).Name = value
+		},
+	},
+	{
+		setting: "clusters[0].kubeconfig",
+		env:     EnvImportKubeconfig,
+		flag:    "import-kubeconfig",
+		usage:   "kubeconfig of the first managed cluster of the options",
+		get: func(o *libgooptions.TestOptionsT) string {
+			if len(o.ManagedClusters) == 0 {
+				return ""
+			}
+			return o.ManagedClusters[0].KubeConfig
+		},
+		set: func(o *libgooptions.TestOptionsT, value string, sources map[string]string) {
+			firstManagedCluster(o, sources).KubeConfig = value
+		},
+	},
+	{
+		setting: "clusters[0].kubecontext",
+		flag:    "import-kubecontext",
+		usage:   "context of the first managed cluster kubeconfig",
+		get: func(o *libgooptions.TestOptionsT) string {
+			if len(o.ManagedClusters) == 0 {
+				return ""
+			}
+			return o.ManagedClusters[0].KubeContext
+		},
+		set: func(o *libgooptions.TestOptionsT, value string, sources map[string]string) {
+			firstManagedCluster(o, sources).KubeContext = value
+		},
+	},
+}
+
+// flagValues holds the flags of optionOverrides by flag name
+var flagValues = map[string]*string{}
+
+// Sources tells where the loaded options come from: the options file, and each option set by
+// a default, an environment variable or a flag rather than by the file
+var Sources map[string]string
+
+// Warnings list the settings of the options file an environment variable overrides, a variable
+// exported in the shell is easily forgotten
+var Warnings []string
+
+// InitFlags adds a flag for every option that can be overridden, next to the library-e2e-go flags
+func InitFlags(flagset *flag.FlagSet) {
+	if flagset == nil {
+		flagset = flag.CommandLine
+	}
+	for _, override := range optionOverrides {
+		usage := override.usage
+		if override.env != "" {
+			usage += fmt.Sprintf(", overrides $%s", override.env)
+		}
+		flagValues[override.flag] = flagset.String(override.flag, "", usage+", overrides "+override.setting+" of the options file")
+	}
+}
+
+// LoadOptions loads the library-e2e-go options and the managed-serviceaccount settings, each layer
+// overriding the previous one: defaults, the options file, environment variables and flags.
+// the file is optionsFile, $OPTIONS or resources/options.yaml. when the hub kubeconfig comes from the
+// environment or a flag, a missing file is generated in memory, with a warning when $OPTIONS named it since
+// the image always sets it. a missing file given by the -options flag is an error
+func LoadOptions(optionsFile string) error {
+	file, fileSource := optionsFile, "flag -options"
+	if file == "" {
+		file, fileSource = os.Getenv(EnvOptions), "env "+EnvOptions
+	}
+	if file == "" {
+		file, fileSource = DefaultOptionsFile, "default"
+	}
+
+	sources := map[string]string{}
+	warnings := []string{}
+	data, err := os.ReadFile(filepath.Clean(file))
+	switch {
+	case err == nil:
+		sources["file"] = fmt.Sprintf("%s (%s)", file, fileSource)
+	case os.IsNotExist(err) && optionsFile == "" && hubKubeconfigOverridden():
+		data = nil
+		sources["file"] = fmt.Sprintf("generated, %s (%s) not found", file, fileSource)
+		if fileSource != "default" {
+			warnings = append(warnings, fmt.Sprintf("$%s=%s does not exist, the options are generated from the environment and the flags",
+				EnvOptions, file))
+		}
+	default:
+		return err
+	}
+
+	libgoOptions := libgooptions.TestOptionsContainer{}
+	if err := yaml.Unmarshal(data, &libgoOptions); err != nil {
+		return fmt.Errorf("invalid options file %s: %v", file, err)
+	}
+	testOptions := TestOptionsContainer{}
+	if err := yaml.Unmarshal(data, &testOptions); err != nil {
+		return fmt.Errorf("invalid options file %s: %v", file, err)
+	}
+
+	for _, override := range optionOverrides {
+		if override.env != "" {
+			if value := os.Getenv(override.env); value != "" {
+				if fileValue := override.get(&libgoOptions.Options); data != nil && fileValue != "" && fileValue != value {
+					warnings = append(warnings, fmt.Sprintf("$%s=%s overrides %s=%s of the options file",
+						override.env, value, override.setting, fileValue))
+				}
+				override.set(&libgoOptions.Options, value, sources)
+				sources[override.setting] = "env " + override.env
+			}
+		}
+		if value := flagValue(override.flag); value != "" {
+			override.set(&libgoOptions.Options, value, sources)
+			sources[override.setting] = "flag -" + override.flag
+		}
+	}
+
+	libgooptions.TestOptions = libgoOptions
+	TestOptions = testOptions
+	Sources = sources
+	Warnings = warnings
+	return nil
+}
+
+// firstManagedCluster returns the cluster the environment and the flags override,
+// a cluster with the default name is added when the options have none
+func firstManagedCluster(o *libgooptions.TestOptionsT, sources map[string]string) *libgooptions.Cluster {
+	if len(o.ManagedClusters) == 0 {
+		o.ManagedClusters = []libgooptions.Cluster{{Name: DefaultManagedClusterName}}
+		sources["clusters[0].name"] = "default"
+	}
+	return &o.ManagedClusters[0]
+}
+
+func hubKubeconfigOverridden() bool {
+	return os.Getenv(EnvKubeconfig) != "" || flagValue("hub-kubeconfig") != ""
+}
+
+func flagValue(name string) string {
+	if value, ok := flagValues[name]; ok && value != nil {
+		return *value
+	}
+	return ""
+}
+
+// PrintOptions writes the loaded options as an options file, secrets redacted and
+// empty settings left out, preceded by the sources of the options and the warnings
+func PrintOptions(w io.Writer) error {
+	libgoOptions, err := toDocument(libgooptions.TestOptions)
+	if err != nil {
+		return err
+	}
+	testOptions, err := toDocument(TestOptions)
+	if err != nil {
+		return err
+	}
+
+	options, _ := libgoOptions["options"].(map[string]interface{})
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	if msa, ok := testOptions["options"].(map[string]interface{}); ok {
+		options["managedServiceAccount"] = msa["managedServiceAccount"]
+	}
+	doc := pruneEmpty(redactSecrets("", map[string]interface{}{"options": options}))
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	settings := []string{}
+	for setting := range Sources {
+		if setting != "file" {
+			settings = append(settings, setting)
+		}
+	}
+	sort.Strings(settings)
+	fmt.Fprintf(w, "# options file: %s\n", Sources["file"])
+	for _, setting := range settings {
+		fmt.Fprintf(w, "# %s: %s\n", setting, Sources[setting])
+	}
+	for _, warning := range Warnings {
+		fmt.Fprintf(w, "# warning: %s\n", warning)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func toDocument(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// isSecret tells whether the option holds a credential, paths to credentials are not secrets
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "file") {
+		return false
+	}
+	for _, word := range []string{"password", "secret", "token", "privatekey", "jsonkey", "accesskeyid"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets replaces the secret values, along with every value of the env of an exec plugin
+func redactSecrets(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if key == "exec" && k == "env" {
+				value[k] = redactAll(child)
+				continue
+			}
+			value[k] = redactSecrets(k, child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactSecrets(key, child)
+		}
+	case string:
+		if value != "" && isSecret(key) {
+			return "<redacted>"
+		}
+	}
+	return v
+}
+
+func redactAll(v interface{}) interface{} {
+	if env, ok := v.(map[string]interface{}); ok {
+		for k := range env {
+			env[k] = "<redacted>"
+		}
+	}
+	return v
+}
+
+// pruneEmpty drops the empty strings, lists and maps, nil when nothing is left
+func pruneEmpty(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			if child = pruneEmpty(child); child == nil {
+				delete(value, k)
+			} else {
+				value[k] = child
+			}
+		}
+		if len(value) == 0 {
+			return nil
+		}
+	case []interface{}:
+		pruned := []interface{}{}
+		for _, child := range value {
+			if child = pruneEmpty(child); child != nil {
+				pruned = append(pruned, child)
+			}
+		}
+		if len(pruned) == 0 {
+			return nil
+		}
+		return pruned
+	case string:
+		if value == "" {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+)
+
+// isolateOptions runs the test in an empty directory, without the environment variables of the loader
+func isolateOptions(t *testing.T) string {
+	for _, env := range []string{EnvOptions, EnvKubeconfig, EnvImportKubeconfig, EnvManagedClusterName} {
+		t.Setenv(env, "")
+	}
+
+	previousLibgoOptions, previousTestOptions := libgooptions.TestOptions, TestOptions
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		libgooptions.TestOptions, TestOptions = previousLibgoOptions, previousTestOptions
+		_ = os.Chdir(wd)
+	})
+	return dir
+}
+
+func TestLoadOptionsGeneratesMissingFiles(t *testing.T) {
+	dir := isolateOptions(t)
+	t.Setenv(EnvKubeconfig, "/env/hub")
+
+	if err := LoadOptions(""); err != nil {
+		t.Fatalf("expected the missing default file to be generated, got %v", err)
+	}
+	if !strings.HasPrefix(Sources["file"], "generated") {
+		t.Errorf("expected generated options, got %q", Sources["file"])
+	}
+	if hub := libgooptions.TestOptions.Options.Hub.KubeConfig; hub != "/env/hub" {
+		t.Errorf("expected the hub kubeconfig of the environment, got %q", hub)
+	}
+
+	// the image always sets $OPTIONS, its missing file is generated with a warning
+	t.Setenv(EnvOptions, filepath.Join(dir, "missing.yaml"))
+	if err := LoadOptions(""); err != nil {
+		t.Fatalf("expected the missing $%s file to be generated, got %v", EnvOptions, err)
+	}
+	if len(Warnings) != 1 || !strings.Contains(Warnings[0], "$OPTIONS="+filepath.Join(dir, "missing.yaml")+" does not exist") {
+		t.Errorf("expected a warning about the missing $%s file, got %v", EnvOptions, Warnings)
+	}
+
+	// a file given by the flag is never replaced by generated options
+	t.Setenv(EnvOptions, "")
+	if err := LoadOptions(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected the missing -options file to be an error, got %v", err)
+	}
+}
+
+func TestLoadOptionsWarnsWhenTheEnvironmentOverridesTheFile(t *testing.T) {
+	dir := isolateOptions(t)
+	file := filepath.Join(dir, "options.yaml")
+	data := "options:\n  hub:\n    kubeconfig: /file/hub\n  clusters:\n  - name: cluster1\n    kubeconfig: /file/cluster1\n"
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvKubeconfig, "/env/hub")
+	// the same value is no override
+	t.Setenv(EnvImportKubeconfig, "/file/cluster1")
+
+	if err := LoadOptions(file); err != nil {
+		t.Fatal(err)
+	}
+	if hub := libgooptions.TestOptions.Options.Hub.KubeConfig; hub != "/env/hub" {
+		t.Errorf("expected the hub kubeconfig of the environment, got %q", hub)
+	}
+	expected := "$KUBECONFIG=/env/hub overrides hub.kubeconfig=/file/hub of the options file"
+	if len(Warnings) != 1 || Warnings[0] != expected {
+		t.Errorf("expected the warning %q, got %v", expected, Warnings)
+	}
+
+	var printed strings.Builder
+	if err := PrintOptions(&printed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(printed.String(), "# warning: "+expected) {
+		t.Errorf("expected the warning in the printed options:\n%s", printed.String())
+	}
+}
@@ -0,0 +1,99 @@
+package options
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type TestOptionsContainer struct {
+	Options TestOptionsT `json:"options"`
+}
+
+// TestOptionsT holds the managed-serviceaccount specific settings that live in
+// the same options file as the library-e2e-go options
+type TestOptionsT struct {
+	ManagedServiceAccount ManagedServiceAccountOptions `json:"managedServiceAccount,omitempty"`
+}
+
+type ManagedServiceAccountOptions struct {
+	// HubOnly reaches the managed clusters through a cluster-admin ManagedServiceAccount
+	// instead of the kubeconfigs of the options, which are then not needed
+	HubOnly bool `json:"hubOnly,omitempty"`
+	// ClusterCredentials replace how the clusters of the options are reached, by cluster name.
+	// a cluster without an entry is reached with the kubeconfig and kubecontext of its options
+	ClusterCredentials []ClusterCredentialOptions `json:"clusterCredentials,omitempty"`
+	// ClusterProxy is how the cluster-proxy specs reach the cluster-proxy user server
+	ClusterProxy ClusterProxyOptions `json:"clusterProxy,omitempty"`
+	// FanOut runs the lifecycle against every cluster of the options instead of the first one selected
+	FanOut bool `json:"fanOut,omitempty"`
+	// ClusterSelection narrows the clusters of the options the specs run against
+	ClusterSelection ClusterSelectionOptions `json:"clusterSelection,omitempty"`
+	// APIVersion of the ManagedServiceAccount API to talk to.
+	// empty uses the preferred version served by the hub, "all" exercises every served version
+	APIVersion string `json:"apiVersion,omitempty"`
+	// ReplaceInstallStrategy lets the placement specs replace an install strategy other than Manual,
+	// which removes the addon from the clusters it placed for the duration of the specs
+	ReplaceInstallStrategy bool `json:"replaceInstallStrategy,omitempty"`
+	// InstallMode is how the feature is enabled on the hub: "mce" through the MultiClusterEngine,
+	// "upstream" from the manifests of the open-cluster-management.io release, empty picks mce when the hub has an MCE
+	InstallMode string `json:"installMode,omitempty"`
+	// UpstreamImage of the addon manager and agent in upstream mode, empty uses the image of the manifests
+	UpstreamImage string `json:"upstreamImage,omitempty"`
+	// InstallNamespace the suite installs the addon agent into, empty uses open-cluster-management-managed-serviceaccount
+	InstallNamespace string `json:"installNamespace,omitempty"`
+	// RotationValidity used by the rotation specs, the kube-apiserver refuses tokens shorter than 10m
+	RotationValidity metav1.Duration `json:"rotationValidity,omitempty"`
+	// RepairTimeout is how long the self-healing specs wait for a damaged ManagedServiceAccount to be repaired
+	RepairTimeout metav1.Duration `json:"repairTimeout,omitempty"`
+}
+
+// ClusterSelectionOptions are checked on top of the Joined and Available conditions
+type ClusterSelectionOptions struct {
+	// LabelSelector the ManagedCluster labels have to match, in the kubectl --selector syntax
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Claims are the ClusterClaims the cluster has to report with these values
+	Claims map[string]string `json:"claims,omitempty"`
+	// MinKubernetesVersion is the lowest kubernetes version accepted, such as v1.27
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+}
+
+// ClusterCredentialOptions reach a cluster with exactly one of a kubeconfig, a bearer token,
+// the in-cluster config or an exec credential plugin
+type ClusterCredentialOptions struct {
+	// Name of the cluster in the clusters of the options
+	Name string `json:"name"`
+	// Kubeconfig file, the context is KubeContext or the current context of the file
+	Kubeconfig  string `json:"kubeconfig,omitempty"`
+	KubeContext string `json:"kubecontext,omitempty"`
+	// APIServerURL is required with a token or an exec plugin, with a kubeconfig it overrides the server of the context
+	APIServerURL string `json:"apiServerURL,omitempty"`
+	// Token or TokenFile is the bearer token sent to the APIServerURL
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	// CAFile trusted for the APIServerURL, empty uses the system roots or the CA of the kubeconfig
+	CAFile string `json:"caFile,omitempty"`
+	// Insecure skips the verification of the APIServerURL certificate
+	Insecure bool `json:"insecure,omitempty"`
+	// InCluster uses the ServiceAccount of the pod the suite runs in
+	InCluster bool `json:"inCluster,omitempty"`
+	// Exec runs a client-go credential plugin to get the credentials for the APIServerURL
+	Exec *ExecCredentialOptions `json:"exec,omitempty"`
+}
+
+type ExecCredentialOptions struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// APIVersion of the ExecCredential, empty uses client.authentication.k8s.io/v1
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+type ClusterProxyOptions struct {
+	// URL of the user server, empty uses the cluster-proxy-addon-user Route of the hub
+	URL string `json:"url,omitempty"`
+	// CAFile trusted for the user server, empty uses the ingress CA of the hub
+	CAFile string `json:"caFile,omitempty"`
+	// Insecure skips the verification of the user server certificate
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+var TestOptions TestOptionsContainer
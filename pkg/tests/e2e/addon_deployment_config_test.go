@@ -0,0 +1,170 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed-serviceaccount addon deployment config", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var configs []*addonv1alpha1.AddOnDeploymentConfig
+	var uid string
+	var addonConfigsChanged bool
+	var previousAddonConfigs []addonv1alpha1.AddOnConfig
+	var nodesLabeled bool
+	var defaultConfigChanged bool
+	var previousDefaultConfig *addonv1alpha1.ConfigReferent
+
+	// the nodes are given the e2e label and nothing taints with the e2e key, so the agent
+	// stays schedulable while the placement is only visible on the Deployment when the config applies
+	nodeLabelKey := "e2e.managed-serviceaccount/node"
+	nodeSelector := map[string]string{nodeLabelKey: "true"}
+	toleration := corev1.Toleration{
+		Key:      "e2e.managed-serviceaccount/dedicated",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+
+	newConfigSpec := func(uid string) addonv1alpha1.AddOnDeploymentConfigSpec {
+		return addonv1alpha1.AddOnDeploymentConfigSpec{
+			NodePlacement: &addonv1alpha1.NodePlacement{
+				NodeSelector: nodeSelector,
+				Tolerations:  []corev1.Toleration{toleration},
+			},
+			CustomizedVariables: []addonv1alpha1.CustomizedVariable{
+				{Name: "E2E_UID", Value: uid},
+			},
+		}
+	}
+
+	expectConfigApplied := func(config *addonv1alpha1.AddOnDeploymentConfig) {
+		Eventually(func() (bool, error) {
+			return utils.IsAddOnDeploymentConfigApplied(hubClient, managedCluster, config.Namespace, config.Name)
+		}, time.Minute*3, time.Second*10).Should(BeTrue())
+
+		Eventually(func(g Gomega) {
+			deployment, err := utils.GetManagedServiceAccountAgentDeployment(hubClient, mcClient, managedCluster)
+			g.Expect(err).Should(BeNil())
+			g.Expect(deployment.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(nodeLabelKey, "true"))
+			g.Expect(deployment.Spec.Template.Spec.Tolerations).To(ContainElement(toleration))
+		}, time.Minute*3, time.Second*10).Should(Succeed())
+
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*5)
+	}
+
+	expectConfigRemoved := func() {
+		Eventually(func(g Gomega) {
+			deployment, err := utils.GetManagedServiceAccountAgentDeployment(hubClient, mcClient, managedCluster)
+			g.Expect(err).Should(BeNil())
+			g.Expect(deployment.Spec.Template.Spec.NodeSelector).NotTo(HaveKey(nodeLabelKey))
+			g.Expect(deployment.Spec.Template.Spec.Tolerations).NotTo(ContainElement(toleration))
+		}, time.Minute*3, time.Second*10).Should(Succeed())
+
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*5)
+	}
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+
+		addon, err := utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		previousAddonConfigs = addon.Spec.Configs
+
+		uid, err = libgooptions.GetUID()
+		Expect(err).Should(BeNil())
+
+		Expect(utils.SetNodesLabel(mcClient, nodeLabelKey, "true")).Should(Succeed())
+		nodesLabeled = true
+
+		for _, name := range []string{"e2e-msa-addon-" + uid, "e2e-msa-default-" + uid} {
+			config, err := utils.CreateAddOnDeploymentConfig(hubClient, managedCluster.Name, name, newConfigSpec(uid))
+			Expect(err).Should(BeNil())
+			configs = append(configs, config)
+		}
+	})
+
+	AfterAll(func() {
+		if defaultConfigChanged {
+			_, err := utils.SetManagedServiceAccountAddonDefaultDeploymentConfig(hubClient, previousDefaultConfig)
+			Expect(err).Should(BeNil())
+		}
+		if addonConfigsChanged && !addonCreated {
+			Expect(utils.SetManagedServiceAccountAddonConfigs(hubClient, managedCluster, previousAddonConfigs)).Should(Succeed())
+		}
+		for _, config := range configs {
+			err := utils.DeleteAddOnDeploymentConfig(hubClient, config.Namespace, config.Name)
+			if !errors.IsNotFound(err) {
+				Expect(err).Should(BeNil())
+			}
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+		if nodesLabeled {
+			Expect(utils.SetNodesLabel(mcClient, nodeLabelKey, "")).Should(Succeed())
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] agent should pick up the AddOnDeploymentConfig of the ManagedClusterAddOn", func() {
+		addonConfigsChanged = true
+		Expect(utils.SetManagedServiceAccountAddonDeploymentConfig(hubClient, managedCluster, configs[0])).Should(Succeed())
+
+		expectConfigApplied(configs[0])
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] agent should drop the placement once the config is detached", func() {
+		Expect(utils.SetManagedServiceAccountAddonDeploymentConfig(hubClient, managedCluster, nil)).Should(Succeed())
+
+		expectConfigRemoved()
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] agent should pick up the default config of the ClusterManagementAddOn", func() {
+		var err error
+		previousDefaultConfig, err = utils.SetManagedServiceAccountAddonDefaultDeploymentConfig(
+			hubClient,
+			&addonv1alpha1.ConfigReferent{Namespace: configs[1].Namespace, Name: configs[1].Name},
+		)
+		Expect(err).Should(BeNil())
+		defaultConfigChanged = true
+
+		expectConfigApplied(configs[1])
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] a change of the custom variables should be applied to the addon", func() {
+		// the agent manifests render no arbitrary variable, the specHash of the config tracks the change instead
+		ref, err := utils.GetAddOnDeploymentConfigReference(hubClient, managedCluster, configs[1].Namespace, configs[1].Name)
+		Expect(err).Should(BeNil())
+		Expect(ref).NotTo(BeNil())
+		Expect(ref.DesiredConfig).NotTo(BeNil())
+		previousSpecHash := ref.DesiredConfig.SpecHash
+
+		Expect(utils.SetAddOnDeploymentConfigCustomizedVariables(hubClient, configs[1].Namespace, configs[1].Name,
+			[]addonv1alpha1.CustomizedVariable{{Name: "E2E_UID", Value: uid + "-updated"}})).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			ref, err := utils.GetAddOnDeploymentConfigReference(hubClient, managedCluster, configs[1].Namespace, configs[1].Name)
+			g.Expect(err).Should(BeNil())
+			g.Expect(ref).NotTo(BeNil())
+			g.Expect(ref.DesiredConfig).NotTo(BeNil())
+			g.Expect(ref.DesiredConfig.SpecHash).NotTo(Equal(previousSpecHash))
+			g.Expect(ref.LastAppliedConfig).NotTo(BeNil())
+			g.Expect(ref.LastAppliedConfig.SpecHash).To(Equal(ref.DesiredConfig.SpecHash))
+		}, time.Minute*3, time.Second*10).Should(Succeed())
+
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*5)
+	})
+})
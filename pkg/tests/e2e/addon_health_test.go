@@ -0,0 +1,87 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed-serviceaccount addon health", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var health *utils.AddonHealth
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+
+		var err error
+		health, err = utils.GetManagedServiceAccountAddonHealth(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should report a healthy set of conditions", func() {
+		Expect(health.IsHealthy()).To(BeTrue(), health.Report())
+		Expect(health.IsConditionTrue(addonv1alpha1.ManagedClusterAddOnManifestApplied)).To(BeTrue(), health.Report())
+		Expect(health.IsConditionTrue(addonv1alpha1.ManagedClusterAddOnRegistrationApplied)).To(BeTrue(), health.Report())
+
+		// Progressing is only True while a config rollout is in flight
+		if progressing := health.Condition(addonv1alpha1.ManagedClusterAddOnConditionProgressing); progressing != nil {
+			Expect(progressing.Status).To(Equal(metav1.ConditionFalse), health.Report())
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should report its health check mode and install namespace", func() {
+		Expect(health.HealthCheckMode).To(BeElementOf(
+			addonv1alpha1.HealthCheckModeLease,
+			addonv1alpha1.HealthCheckModeCustomized,
+		), health.Report())
+
+		namespace, err := utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		Expect(health.InstallNamespace).To(Equal(namespace), health.Report())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should report its related objects and registrations", func() {
+		Expect(health.HasRelatedObject(
+			"addon.open-cluster-management.io", "clustermanagementaddons", "", "managed-serviceaccount",
+		)).To(BeTrue(), health.Report())
+
+		// the agent reports tokens back to the hub with a client certificate
+		registration := health.Registration(certificatesv1.KubeAPIServerClientSignerName)
+		Expect(registration).NotTo(BeNil(), health.Report())
+		Expect(registration.Subject.User).NotTo(BeEmpty(), health.Report())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] failed addon wait should explain itself with the health report", func() {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+		defer cancel()
+
+		_, err := utils.WaitForAddonConditions(ctx, hubClient, managedCluster, "E2ENeverReported")
+		Expect(err).Should(HaveOccurred())
+
+		var waitErr *utils.WaitError
+		Expect(errors.As(err, &waitErr)).To(BeTrue(), "expecting a WaitError, got %v", err)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("E2ENeverReported"))
+		Expect(err.Error()).To(ContainSubstring("Available=True"))
+		Expect(err.Error()).To(ContainSubstring("registrations:"))
+	})
+})
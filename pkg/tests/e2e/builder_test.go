@@ -0,0 +1,123 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount options", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+	var uid string
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		var err error
+		uid, err = libgooptions.GetUID()
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] able to create a named managed-serviceaccount with custom validity", func() {
+		name := "e2e-named-" + uid
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithName(name),
+			utils.WithRotationValidity(time.Hour*2),
+			utils.WithAnnotations(map[string]string{"e2e.options/annotation": uid}),
+		)
+		Expect(err).Should(BeNil())
+		deferDeleteManagedServiceAccount(hubClient, managedCluster, name)
+		Expect(createdManagedServiceAccount.Name).To(Equal(name))
+		Expect(createdManagedServiceAccount.Spec.Rotation.Validity.Duration).To(Equal(time.Hour * 2))
+		Expect(createdManagedServiceAccount.Annotations).To(HaveKeyWithValue("e2e.options/annotation", uid))
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, name, time.Minute*1)
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] able to issue a valid token with rotation disabled", func() {
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-no-rotation-"),
+			utils.WithRotationEnabled(false),
+		)
+		Expect(err).Should(BeNil())
+		name := createdManagedServiceAccount.Name
+		deferDeleteManagedServiceAccount(hubClient, managedCluster, name)
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, name, time.Minute*1)
+
+		token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, name)
+		Expect(err).Should(BeNil())
+		username, err := utils.GetManagedServiceAccountUserName(hubClient, managedCluster, name)
+		Expect(err).Should(BeNil())
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, token, username)).Should(BeTrue())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] able to create a labelled fleet of managed-serviceaccounts", func() {
+		fleetLabels := map[string]string{"e2e.options/fleet": uid}
+		names := []string{}
+		for i := 0; i < 3; i++ {
+			createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+				hubClient,
+				managedCluster,
+				utils.WithGenerateName("e2e-fleet-"),
+				utils.WithLabels(fleetLabels),
+			)
+			Expect(err).Should(BeNil())
+			deferDeleteManagedServiceAccount(hubClient, managedCluster, createdManagedServiceAccount.Name)
+			names = append(names, createdManagedServiceAccount.Name)
+		}
+
+		Eventually(func(g Gomega) {
+			msaList, err := utils.ListManagedServiceAccount(hubClient, managedCluster)
+			g.Expect(err).Should(BeNil())
+
+			labelled := []string{}
+			for _, msa := range msaList.Items {
+				if msa.Labels["e2e.options/fleet"] == uid {
+					labelled = append(labelled, msa.Name)
+				}
+			}
+			g.Expect(labelled).To(ConsistOf(names))
+		}, time.Minute*1, time.Second*5).Should(Succeed())
+
+		for _, name := range names {
+			waitForManagedServiceAccountReady(hubClient, managedCluster, name, time.Minute*1)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] managed-serviceaccount with ttl should be deleted after expiring", func() {
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-ttl-"),
+			utils.WithTTLSecondsAfterCreation(30),
+		)
+		Expect(err).Should(BeNil())
+		deferDeleteManagedServiceAccount(hubClient, managedCluster, createdManagedServiceAccount.Name)
+		Expect(createdManagedServiceAccount.Spec.TTLSecondsAfterCreation).NotTo(BeNil())
+
+		Eventually(func() bool {
+			return utils.DoesManagedServiceAccountExist(hubClient, managedCluster, createdManagedServiceAccount.Name)
+		}, time.Minute*3, time.Second*10).Should(BeFalse())
+	})
+})
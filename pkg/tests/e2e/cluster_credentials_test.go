@@ -0,0 +1,37 @@
+package base_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed cluster credentials", Ordered, func() {
+	var managedCluster *clusterv1.ManagedCluster
+
+	BeforeAll(func() {
+		_, _, managedCluster = setupClients()
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] client for a cluster missing from the options should be refused", func() {
+		// the credentials of the other clusters are not checked, hub-only runs list them by name only
+		_, err := clients.GetManagedClusterDynamicClient("e2e-not-in-options")
+
+		var unknownErr *clients.UnknownClusterError
+		Expect(err).To(BeAssignableToTypeOf(unknownErr))
+		Expect(err.Error()).To(ContainSubstring("e2e-not-in-options"))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selected cluster should be reachable with its credentials", func() {
+		if options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+			Skip("hub-only runs reach the managed clusters without credentials of their own")
+		}
+
+		registry, err := clients.NewClusterRegistryFromOptions()
+		Expect(err).Should(BeNil())
+		Expect(registry.Names()).To(ContainElement(managedCluster.Name))
+		Expect(registry.Probe(managedCluster.Name)).Should(Succeed())
+	})
+})
@@ -0,0 +1,195 @@
+package base_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount access through cluster-proxy", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var managedServiceAccountName string
+	var username string
+	// nil when cluster-proxy is available for the cluster
+	var notInstalledErr error
+	var proxyConfig *rest.Config
+	var workNames []string
+
+	// requireClusterProxy skips the spec when the cluster cannot be reached through cluster-proxy
+	requireClusterProxy := func() {
+		if notInstalledErr != nil {
+			Skip(notInstalledErr.Error())
+		}
+	}
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-proxy-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		username, err = utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		notInstalledErr = utils.CheckClusterProxyInstalled(hubClient, managedCluster)
+		if notInstalledErr != nil {
+			Expect(notInstalledErr).To(BeAssignableToTypeOf(&utils.ClusterProxyNotInstalledError{}))
+		}
+	})
+
+	AfterAll(func() {
+		for _, workName := range workNames {
+			err := utils.DeleteManifestWork(hubClient, managedCluster, workName)
+			if !errors.IsNotFound(err) {
+				Expect(err).Should(BeNil())
+			}
+		}
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] client builder should report when cluster-proxy is not installed", func() {
+		// no cluster-proxy addon is ever installed for a cluster the hub does not know, so this runs on every hub
+		unregistered := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "e2e-not-registered"}}
+
+		// the endpoint is never dialed, the builder checks the addon first
+		endpoint := &clients.ClusterProxyEndpoint{URL: "https://cluster-proxy.invalid"}
+		_, err := clients.GetClusterProxyRestConfig(hubClient, endpoint, unregistered, managedServiceAccountName)
+		Expect(err).To(BeAssignableToTypeOf(&utils.ClusterProxyNotInstalledError{}))
+		Expect(err.Error()).To(ContainSubstring("cluster-proxy"))
+
+		// an error naming the selected cluster means the hub has cluster-proxy too
+		selectedErr, _ := notInstalledErr.(*utils.ClusterProxyNotInstalledError)
+		if notInstalledErr == nil || selectedErr.Cluster != "" {
+			// the hub has cluster-proxy, so the missing addon of the cluster is reported
+			Expect(err.(*utils.ClusterProxyNotInstalledError).Cluster).To(Equal(unregistered.Name))
+			Expect(err.Error()).To(ContainSubstring("ManagedClusterAddOn cluster-proxy not found"))
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] able to build a client through the cluster-proxy user server", func() {
+		requireClusterProxy()
+
+		proxyOptions := options.TestOptions.Options.ManagedServiceAccount.ClusterProxy
+		endpoint, err := clients.GetClusterProxyEndpoint(hubClient, proxyOptions.URL, proxyOptions.CAFile, proxyOptions.Insecure)
+		Expect(err).Should(BeNil())
+
+		proxyConfig, err = clients.GetClusterProxyRestConfig(hubClient, endpoint, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(proxyConfig.Host).To(HaveSuffix("/" + managedCluster.Name))
+		Expect(proxyConfig.BearerToken).NotTo(BeEmpty())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] token should pass a TokenReview made over cluster-proxy", func() {
+		requireClusterProxy()
+
+		// creating TokenReviews needs system:auth-delegator
+		serviceAccountNamespace, err := utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		work := utils.NewClusterRoleBindingManifestWork(
+			managedCluster,
+			managedServiceAccountName+"-auth-delegator",
+			serviceAccountNamespace,
+			managedServiceAccountName,
+			"system:auth-delegator",
+		)
+		_, err = utils.CreateManifestWork(hubClient, work)
+		Expect(err).Should(BeNil())
+		workNames = append(workNames, work.Name)
+
+		Eventually(func() bool {
+			return utils.IsManifestWorkAvailable(hubClient, managedCluster, work.Name)
+		}, time.Minute*2, time.Second*10).Should(BeTrue())
+
+		proxyClient, err := dynamic.NewForConfig(proxyConfig)
+		Expect(err).Should(BeNil())
+
+		Eventually(func() (bool, error) {
+			return utils.ValidateManagedServiceAccountToken(proxyClient, proxyConfig.BearerToken, username)
+		}, time.Minute*2, time.Second*10).Should(BeTrue())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] access over cluster-proxy should follow the RBAC delivered by ManifestWork", func() {
+		requireClusterProxy()
+
+		proxyClient, err := dynamic.NewForConfig(proxyConfig)
+		Expect(err).Should(BeNil())
+		listPods := authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      "list",
+			Resource:  "pods",
+		}
+
+		review, err := utils.CreateSelfSubjectAccessReview(proxyClient, listPods)
+		Expect(err).Should(BeNil())
+		Expect(review.Status.Allowed).To(BeFalse())
+
+		work, err := utils.GrantManagedServiceAccountPermissions(
+			hubClient,
+			managedCluster,
+			managedServiceAccountName,
+			"default",
+			[]rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}}},
+		)
+		Expect(err).Should(BeNil())
+		workNames = append(workNames, work.Name)
+
+		Eventually(func() (bool, error) {
+			review, err := utils.CreateSelfSubjectAccessReview(proxyClient, listPods)
+			if err != nil {
+				return false, err
+			}
+			return review.Status.Allowed, nil
+		}, time.Minute*2, time.Second*10).Should(BeTrue())
+
+		proxyKubeClient, err := kubernetes.NewForConfig(proxyConfig)
+		Expect(err).Should(BeNil())
+		_, err = proxyKubeClient.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
+		Expect(err).Should(BeNil())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] invalid token should be rejected over cluster-proxy", func() {
+		requireClusterProxy()
+
+		invalidConfig := rest.CopyConfig(proxyConfig)
+		invalidConfig.BearerToken = "invalid"
+		invalidClient, err := dynamic.NewForConfig(invalidConfig)
+		Expect(err).Should(BeNil())
+
+		_, err = utils.CreateSelfSubjectAccessReview(invalidClient, authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      "list",
+			Resource:  "pods",
+		})
+		Expect(errors.IsUnauthorized(err)).To(BeTrue(), "expecting unauthorized, got %v", err)
+	})
+})
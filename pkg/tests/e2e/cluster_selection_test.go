@@ -0,0 +1,101 @@
+package base_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed cluster selection", Ordered, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selected cluster should be an options cluster that is Joined and Available", func() {
+		Expect(utils.OptionsClusterNames()).To(ContainElement(managedCluster.Name))
+		Expect(meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)).To(BeTrue())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selection should explain why a cluster is rejected by its labels", func() {
+		selector := utils.ClusterSelector{
+			Names:         []string{managedCluster.Name},
+			LabelSelector: "e2e.managed-serviceaccount/never-set=true",
+		}
+		_, err := utils.SelectManagedCluster(hubClient, selector)
+
+		var selectionErr *utils.ClusterSelectionError
+		Expect(err).To(BeAssignableToTypeOf(selectionErr))
+		Expect(err.Error()).To(ContainSubstring(managedCluster.Name))
+		Expect(err.Error()).To(ContainSubstring("e2e.managed-serviceaccount/never-set=true"))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selection should reject clusters that are not registered", func() {
+		selector := utils.ClusterSelector{
+			Names: []string{"e2e-not-registered", managedCluster.Name},
+		}
+		selected, err := utils.SelectManagedClusters(hubClient, selector, 2)
+		Expect(selected).To(BeNil())
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("e2e-not-registered: not registered on the hub"))
+		Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("2 managed cluster(s) wanted, 1 matched (%s)", managedCluster.Name)))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selection should pick no cluster without names", func() {
+		selected, err := utils.SelectManagedClusters(hubClient, utils.ClusterSelector{}, 1)
+		Expect(selected).To(BeNil())
+
+		var selectionErr *utils.ClusterSelectionError
+		Expect(err).To(BeAssignableToTypeOf(selectionErr))
+		Expect(err.Error()).To(ContainSubstring("1 managed cluster(s) wanted, 0 matched, no candidate found"))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selection should filter on ClusterClaims", func() {
+		if len(managedCluster.Status.ClusterClaims) == 0 {
+			Skip(fmt.Sprintf("managed cluster %s reports no ClusterClaims", managedCluster.Name))
+		}
+		claim := managedCluster.Status.ClusterClaims[0]
+
+		selected, err := utils.SelectManagedCluster(hubClient, utils.ClusterSelector{
+			Names:  []string{managedCluster.Name},
+			Claims: map[string]string{claim.Name: claim.Value},
+		})
+		Expect(err).Should(BeNil())
+		Expect(selected.Name).To(Equal(managedCluster.Name))
+
+		_, err = utils.SelectManagedCluster(hubClient, utils.ClusterSelector{
+			Names:  []string{managedCluster.Name},
+			Claims: map[string]string{claim.Name: claim.Value + "-e2e"},
+		})
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("ClusterClaim " + claim.Name))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] selection should filter on the kubernetes version", func() {
+		current, err := version.ParseGeneric(managedCluster.Status.Version.Kubernetes)
+		Expect(err).Should(BeNil())
+
+		selected, err := utils.SelectManagedCluster(hubClient, utils.ClusterSelector{
+			Names:                []string{managedCluster.Name},
+			MinKubernetesVersion: fmt.Sprintf("v%d.%d", current.Major(), current.Minor()),
+		})
+		Expect(err).Should(BeNil())
+		Expect(selected.Name).To(Equal(managedCluster.Name))
+
+		_, err = utils.SelectManagedCluster(hubClient, utils.ClusterSelector{
+			Names:                []string{managedCluster.Name},
+			MinKubernetesVersion: fmt.Sprintf("v%d.%d", current.Major(), current.Minor()+1),
+		})
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("older than"))
+	})
+})
@@ -0,0 +1,258 @@
+package base_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// expectSameFields checks that two reads of the same object through different api versions,
+// as served by the hub, carry the same value at each field path
+func expectSameFields(g Gomega, expected, actual *unstructured.Unstructured, fields ...[]string) {
+	for _, field := range fields {
+		expectedValue, _, err := unstructured.NestedFieldNoCopy(expected.Object, field...)
+		g.Expect(err).Should(BeNil())
+		actualValue, _, err := unstructured.NestedFieldNoCopy(actual.Object, field...)
+		g.Expect(err).Should(BeNil())
+		g.Expect(actualValue).To(Equal(expectedValue), "field %s", strings.Join(field, "."))
+	}
+}
+
+// expectField checks the value the hub serves at the field path
+func expectField(g Gomega, u *unstructured.Unstructured, expected interface{}, field ...string) {
+	value, found, err := unstructured.NestedFieldNoCopy(u.Object, field...)
+	g.Expect(err).Should(BeNil())
+	g.Expect(found).To(BeTrue(), "field %s not served through %s", strings.Join(field, "."), u.GetAPIVersion())
+	g.Expect(value).To(Equal(expected), "field %s served through %s", strings.Join(field, "."), u.GetAPIVersion())
+}
+
+// expectServedVersion checks the object was converted by the hub to the version it was read through
+func expectServedVersion(g Gomega, u *unstructured.Unstructured, version string) {
+	g.Expect(u.GetAPIVersion()).To(Equal(utils.ManagedServiceAccountGroup + "/" + version))
+}
+
+var (
+	conversionMetadataFields = [][]string{{"metadata", "labels"}, {"metadata", "annotations"}}
+	conversionSpecFields     = [][]string{{"spec", "rotation", "enabled"}, {"spec", "rotation", "validity"}}
+	conversionStatusFields   = [][]string{
+		{"status", "conditions"},
+		{"status", "tokenSecretRef"},
+		{"status", "expirationTimestamp"},
+	}
+)
+
+var _ = Describe("ManagedServiceAccount conversion", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+
+		hubKubeClient, err := clients.GetHubKubeClient()
+		Expect(err).Should(BeNil())
+
+		served, _, err := utils.GetServedManagedServiceAccountVersions(hubKubeClient.Discovery())
+		Expect(err).Should(BeNil())
+		for _, version := range utils.ManagedServiceAccountVersions {
+			if !slices.Contains(served, version) {
+				Skip("conversion needs every ManagedServiceAccount version served, hub serves " + fmt.Sprint(served))
+			}
+		}
+	})
+
+	AfterAll(func() {
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	for _, versions := range [][]string{
+		{utils.ManagedServiceAccountVersionV1alpha1, utils.ManagedServiceAccountVersionV1beta1},
+		{utils.ManagedServiceAccountVersionV1beta1, utils.ManagedServiceAccountVersionV1alpha1},
+	} {
+		createVersion, otherVersion := versions[0], versions[1]
+
+		Context(fmt.Sprintf("created through %s and managed through %s", createVersion, otherVersion), Ordered, func() {
+			var managedServiceAccountName string
+
+			// the last spec deletes the object through the other version, this only finds it when a spec failed before
+			AfterAll(func() {
+				if managedServiceAccountName == "" {
+					return
+				}
+				err := utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+				if !errors.IsNotFound(err) {
+					Expect(err).Should(BeNil())
+				}
+			})
+
+			It("[P2][Sev2][cluster-lifecycle] spec and status survive the conversion", func() {
+				By("creating a ManagedServiceAccount through " + createVersion)
+				createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithVersion(
+					hubClient,
+					createVersion,
+					managedCluster,
+					utils.WithGenerateName("e2e-conversion-"),
+					utils.WithLabels(map[string]string{"e2e.conversion/label": createVersion}),
+					utils.WithAnnotations(map[string]string{"e2e.conversion/annotation": createVersion}),
+					utils.WithRotationEnabled(true),
+					utils.WithRotationValidity(time.Hour*3),
+				)
+				Expect(err).Should(BeNil())
+				managedServiceAccountName = createdManagedServiceAccount.Name
+
+				waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+				By("reading the ManagedServiceAccount through " + otherVersion + " as served by the hub")
+				Eventually(func(g Gomega) {
+					created, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, createVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+					converted, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, otherVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+
+					// both reads must observe the same revision of the object
+					g.Expect(converted.GetResourceVersion()).To(Equal(created.GetResourceVersion()))
+					expectServedVersion(g, converted, otherVersion)
+
+					expectField(g, converted, createVersion, "metadata", "labels", "e2e.conversion/label")
+					expectField(g, converted, createVersion, "metadata", "annotations", "e2e.conversion/annotation")
+					expectField(g, converted, true, "spec", "rotation", "enabled")
+					validity, _, err := unstructured.NestedString(converted.Object, "spec", "rotation", "validity")
+					g.Expect(err).Should(BeNil())
+					g.Expect(time.ParseDuration(validity)).To(Equal(time.Hour * 3))
+
+					conditions, _, err := unstructured.NestedSlice(converted.Object, "status", "conditions")
+					g.Expect(err).Should(BeNil())
+					g.Expect(conditions).NotTo(BeEmpty())
+					tokenSecretName, _, err := unstructured.NestedString(converted.Object, "status", "tokenSecretRef", "name")
+					g.Expect(err).Should(BeNil())
+					g.Expect(tokenSecretName).NotTo(BeEmpty())
+					expectSameFields(g, created, converted, conversionMetadataFields...)
+					expectSameFields(g, created, converted, conversionSpecFields...)
+					expectSameFields(g, created, converted, conversionStatusFields...)
+				}, time.Minute*1, time.Second*5).Should(Succeed())
+			})
+
+			It("[P2][Sev2][cluster-lifecycle] updates through the other version are preserved", func() {
+				By("updating labels, annotations and rotation through " + otherVersion)
+				Eventually(func() error {
+					uManagedServiceAccount, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, otherVersion, managedCluster, managedServiceAccountName)
+					if err != nil {
+						return err
+					}
+
+					uManagedServiceAccount.SetLabels(map[string]string{"e2e.conversion/label": otherVersion})
+					uManagedServiceAccount.SetAnnotations(map[string]string{"e2e.conversion/annotation": otherVersion})
+					if err := unstructured.SetNestedField(uManagedServiceAccount.Object, "2h0m0s", "spec", "rotation", "validity"); err != nil {
+						return err
+					}
+
+					_, err = utils.UpdateUnstructuredManagedServiceAccount(hubClient, uManagedServiceAccount)
+					return err
+				}, time.Minute*1, time.Second*5).Should(BeNil())
+
+				By("reading the update back through " + createVersion)
+				Eventually(func(g Gomega) {
+					converted, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, createVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+					current, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, otherVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+					g.Expect(converted.GetResourceVersion()).To(Equal(current.GetResourceVersion()))
+					expectServedVersion(g, converted, createVersion)
+
+					expectField(g, converted, otherVersion, "metadata", "labels", "e2e.conversion/label")
+					expectField(g, converted, otherVersion, "metadata", "annotations", "e2e.conversion/annotation")
+					expectField(g, converted, "2h0m0s", "spec", "rotation", "validity")
+					expectField(g, converted, true, "spec", "rotation", "enabled")
+					expectSameFields(g, current, converted, conversionMetadataFields...)
+					expectSameFields(g, current, converted, conversionSpecFields...)
+					expectSameFields(g, current, converted, conversionStatusFields...)
+				}, time.Minute*1, time.Second*5).Should(Succeed())
+			})
+
+			It("[P2][Sev2][cluster-lifecycle] status updates through the other version are preserved", func() {
+				// a condition the controller does not own, so it is left alone
+				conditionType := "E2EConversion" + strings.ToUpper(otherVersion[:1]) + otherVersion[1:]
+
+				By("adding the " + conditionType + " condition through " + otherVersion)
+				Eventually(func() error {
+					uManagedServiceAccount, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, otherVersion, managedCluster, managedServiceAccountName)
+					if err != nil {
+						return err
+					}
+
+					conditions, _, err := unstructured.NestedSlice(uManagedServiceAccount.Object, "status", "conditions")
+					if err != nil {
+						return err
+					}
+					conditions = append(conditions, map[string]interface{}{
+						"type":               conditionType,
+						"status":             string(metav1.ConditionTrue),
+						"reason":             "UpdatedThrough" + strings.ToUpper(otherVersion[:1]) + otherVersion[1:],
+						"message":            "status written through " + otherVersion,
+						"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+					})
+					if err := unstructured.SetNestedSlice(uManagedServiceAccount.Object, conditions, "status", "conditions"); err != nil {
+						return err
+					}
+
+					_, err = utils.UpdateUnstructuredManagedServiceAccountStatus(hubClient, uManagedServiceAccount)
+					return err
+				}, time.Minute*1, time.Second*5).Should(BeNil())
+
+				By("reading the condition back through " + createVersion)
+				Eventually(func(g Gomega) {
+					converted, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, createVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+					expectServedVersion(g, converted, createVersion)
+
+					conditions, _, err := unstructured.NestedSlice(converted.Object, "status", "conditions")
+					g.Expect(err).Should(BeNil())
+					g.Expect(conditions).To(ContainElement(And(
+						HaveKeyWithValue("type", conditionType),
+						HaveKeyWithValue("status", string(metav1.ConditionTrue)),
+						HaveKeyWithValue("message", "status written through "+otherVersion),
+					)))
+
+					current, err := utils.GetUnstructuredManagedServiceAccount(
+						hubClient, otherVersion, managedCluster, managedServiceAccountName)
+					g.Expect(err).Should(BeNil())
+					g.Expect(converted.GetResourceVersion()).To(Equal(current.GetResourceVersion()))
+					expectSameFields(g, current, converted, conversionStatusFields...)
+				}, time.Minute*1, time.Second*5).Should(Succeed())
+			})
+
+			It("[P2][Sev2][cluster-lifecycle] able to delete through the other version", func() {
+				err := utils.DeleteManagedServiceAccountWithVersion(
+					hubClient, otherVersion, managedCluster, managedServiceAccountName)
+				Expect(err).Should(BeNil())
+
+				Eventually(func() bool {
+					_, err := utils.GetManagedServiceAccountWithVersion(
+						hubClient, createVersion, managedCluster, managedServiceAccountName)
+					return errors.IsNotFound(err)
+				}, time.Minute*2, time.Second*10).Should(BeTrue())
+			})
+		})
+	}
+})
@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	libgocmd "github.com/stolostron/library-e2e-go/pkg/cmd"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
 	"k8s.io/klog"
 )
 
@@ -14,8 +15,22 @@ func init() {
 	klog.InitFlags(nil)
 
 	libgocmd.InitFlags(nil)
+	options.InitFlags(nil)
 }
 
+// with ginkgo -p the snapshot is taken and restored once, on the first process
+var _ = SynchronizedBeforeSuite(func() []byte {
+	printEffectiveOptions()
+	snapshotManagedServiceAccountState()
+	probeClusterCredentials()
+	return nil
+}, func([]byte) {})
+
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	removeManagedClusterAccess()
+	restoreManagedServiceAccountState()
+})
+
 func TestBase(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Base Suite")
@@ -1,60 +1,76 @@
 package base_test
 
 import (
-	"context"
+	"slices"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
-	libgocmd "github.com/stolostron/library-e2e-go/pkg/cmd"
-	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
-	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
 	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
 var _ = Describe("e2e", Ordered, func() {
-	var hubClient dynamic.Interface
-	var mcClient dynamic.Interface
-	var managedCluster *clusterv1.ManagedCluster
-	var managedServiceAccountName string
-
 	BeforeAll(func() {
-		//initialize options
-		err := libgooptions.LoadOptions(libgocmd.End2End.OptionsFile)
+		clusterNames, err := fanOutClusterNames()
 		Expect(err).To(BeNil())
+		if len(clusterNames) > 0 {
+			Skip("the lifecycle runs against every cluster in the e2e fleet specs")
+		}
+	})
 
-		//initialize hub dynamic client
-		hubClient, err = clients.GetHubDynamicClient()
-		Expect(err).Should(BeNil())
+	lifecycleSpecs(setupClients)
+})
 
-		//find a managed cluster to do the test on
-		managedCluster, err = utils.GetImportedCluster(hubClient)
-		Expect(err).Should(BeNil())
-		Expect(managedCluster).ShouldNot(BeNil())
+// e2e fleet runs the lifecycle once per cluster of the options when managedServiceAccount.fanOut is set,
+// each cluster is its own Ordered container so ginkgo -p runs them in parallel and reports them apart
+var _ = Describe("e2e fleet", func() {
+	clusterNames, err := fanOutClusterNames()
+	if err != nil {
+		// the options may ask for the fleet, falling back to the single cluster lifecycle would hide it
+		It("[P1][Sev1][cluster-lifecycle] options should load to list the clusters of the fleet", func() {
+			Fail("fail to load the options: " + err.Error())
+		})
+		return
+	}
+
+	for _, clusterName := range clusterNames {
+		clusterName := clusterName
+
+		Context("on cluster "+clusterName, Ordered, func() {
+			lifecycleSpecs(func() (dynamic.Interface, dynamic.Interface, *clusterv1.ManagedCluster) {
+				return setupClientsFor(clusterName)
+			})
+		})
+	}
+})
 
-		//initialize managedcluster dynamic client
-		mcClient, err = clients.GetManagedClusterDynamicClient(managedCluster.Name)
-		Expect(err).Should(BeNil())
+// lifecycleSpecs registers the lifecycle specs against the cluster returned by setup,
+// it is called in the body of an Ordered container
+func lifecycleSpecs(setup func() (dynamic.Interface, dynamic.Interface, *clusterv1.ManagedCluster)) {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var managedServiceAccountVersions []string
+	// only an addon installed by these specs is removed by them
+	var addonInstalled bool
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setup()
 	})
 	It("[P1][Sev1][cluster-lifecycle] able to enable managed-serviceaccount addon on hub", func() {
 		By("Enabling ManagedServiceAccount feature in MCE")
 		err := utils.EnableManagedServiceAccountFeature(hubClient)
 		Expect(err).Should(BeNil(), "fail to enable the feature")
+		Expect(utils.IsManagedServiceAccountFeatureEnabled(hubClient)).To(BeTrue())
 
 		// check if clustermanagementaddon is created
 		By("Waiting ClusterManagementAddon managed-serviceaccount to appear")
-		gvr := schema.GroupVersionResource{
-			Group:    "addon.open-cluster-management.io",
-			Version:  "v1alpha1",
-			Resource: "clustermanagementaddons",
-		}
 		Eventually(func() error {
-			_, err := hubClient.Resource(gvr).Get(context.TODO(), "managed-serviceaccount", v1.GetOptions{})
+			_, err := utils.GetManagedServiceAccountClusterManagementAddon(hubClient)
 			return err
 		}, time.Minute*3, time.Second*10).Should(BeNil())
 
@@ -77,78 +93,128 @@ var _ = Describe("e2e", Ordered, func() {
 		managedServiceAccountAddon, err = utils.CreateManagedServiceAccountAddon(
 			hubClient,
 			managedCluster,
+			options.TestOptions.Options.ManagedServiceAccount.InstallNamespace,
 		)
 		Expect(err).Should(BeNil())
 		Expect(managedServiceAccountAddon).NotTo(BeNil())
+		addonInstalled = true
 
 		//eventually managed-serviceaccount addon should be availble
-		Eventually(func() bool {
-			return utils.IsManagedServiceAccountAddonAvailable(hubClient, managedCluster)
-		}, time.Minute*10, time.Second*10).Should(BeTrue())
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
 	})
 
-	It("[P1][Sev1][cluster-lifecycle] able to create managed-serviceaccount", func() {
-		By("creating a ManagedServiceAccount in ManagedCluster namespace")
-		//create managed serviceaccount
-		createdManagedServiceAccount, err := utils.CreateManagedServiceAccount(
-			hubClient,
-			managedCluster,
-			"e2e-",
-		)
-		Expect(err).Should(BeNil())
-		Expect(createdManagedServiceAccount).ShouldNot(BeNil())
-
-		//eventually managed serviceaccount status condition should contain
-		// - "TokenReported"
-		// - "SecretCreated"
-		Eventually(func() bool {
-			return utils.IsManagedServiceAccountComplete(
-				hubClient,
-				managedCluster,
-				createdManagedServiceAccount.Name,
-			)
-		}, time.Minute*1, time.Second*10).Should(BeTrue())
-
-		managedServiceAccountName = createdManagedServiceAccount.Name
-	})
-
-	It("[P1][Sev1][cluster-lifecycle] managed serviceaccount should generated valid token secret", func() {
-		token, err := utils.GetManagedServiceAccountToken(
-			hubClient,
-			managedCluster,
-			managedServiceAccountName,
-		)
-		Expect(err).Should(BeNil())
-		Expect(token).ShouldNot(BeEmpty())
-
-		username, err := utils.GetManagedServiceAccountUserName(
-			hubClient,
-			managedCluster,
-			managedServiceAccountName,
-		)
-		Expect(err).Should(BeNil())
-		Expect(username).ShouldNot(BeEmpty())
-
-		Expect(utils.ValidateManagedServiceAccountToken(mcClient, token, username)).Should(BeTrue())
-	})
-
-	It("[P1][Sev1][cluster-lifecycle] able to delete managed-serviceaccount", func() {
-		//managed-serviceaccount addon shouldnt already be installed
-		managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
-		Expect(err).Should(BeNil())
-		Expect(managedServiceAccount).NotTo(BeNil())
-
-		//install managed-serviceaccount addon
-		err = utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
-		Expect(err).Should(BeNil())
-
-		//eventually managed-serviceaccount addon to be deleted
-		Eventually(func() bool {
-			return utils.DoesManagedServiceAccountExist(hubClient, managedCluster, managedServiceAccountName)
-		}, time.Minute*10, time.Second*10).Should(BeFalse())
-	})
+	for _, version := range utils.ManagedServiceAccountVersions {
+		version := version
+
+		Context("ManagedServiceAccount "+version, Ordered, func() {
+			var managedServiceAccountName string
+
+			BeforeAll(func() {
+				if managedServiceAccountVersions == nil {
+					managedServiceAccountVersions = negotiateManagedServiceAccountVersions()
+				}
+
+				if !slices.Contains(managedServiceAccountVersions, version) {
+					Skip("ManagedServiceAccount " + version + " is not selected")
+				}
+				Expect(utils.SetManagedServiceAccountVersion(version)).Should(Succeed())
+			})
+
+			AfterAll(func() {
+				// restore the default version for the specs that follow
+				if len(managedServiceAccountVersions) > 0 {
+					Expect(utils.SetManagedServiceAccountVersion(managedServiceAccountVersions[0])).Should(Succeed())
+				}
+			})
+
+			It("[P1][Sev1][cluster-lifecycle] able to create managed-serviceaccount", func() {
+				By("creating a ManagedServiceAccount in ManagedCluster namespace")
+				//create managed serviceaccount
+				createdManagedServiceAccount, err := utils.CreateManagedServiceAccount(
+					hubClient,
+					managedCluster,
+					"e2e-",
+				)
+				Expect(err).Should(BeNil())
+				Expect(createdManagedServiceAccount).ShouldNot(BeNil())
+
+				//eventually managed serviceaccount status condition should contain
+				// - "TokenReported"
+				// - "SecretCreated"
+				waitForManagedServiceAccountReady(hubClient, managedCluster, createdManagedServiceAccount.Name, time.Minute*1)
+
+				managedServiceAccountName = createdManagedServiceAccount.Name
+			})
+
+			It("[P1][Sev1][cluster-lifecycle] managed serviceaccount should generated valid token secret", func() {
+				token, err := utils.GetManagedServiceAccountToken(
+					hubClient,
+					managedCluster,
+					managedServiceAccountName,
+				)
+				Expect(err).Should(BeNil())
+				Expect(token).ShouldNot(BeEmpty())
+
+				username, err := utils.GetManagedServiceAccountUserName(
+					hubClient,
+					managedCluster,
+					managedServiceAccountName,
+				)
+				Expect(err).Should(BeNil())
+				Expect(username).ShouldNot(BeEmpty())
+
+				Expect(utils.ValidateManagedServiceAccountToken(mcClient, token, username)).Should(BeTrue())
+			})
+
+			It("[P1][Sev1][cluster-lifecycle] able to delete managed-serviceaccount", func() {
+				//managed-serviceaccount addon shouldnt already be installed
+				managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+				Expect(err).Should(BeNil())
+				Expect(managedServiceAccount).NotTo(BeNil())
+				Expect(managedServiceAccount.Status.TokenSecretRef).NotTo(BeNil())
+				secretName := managedServiceAccount.Status.TokenSecretRef.Name
+
+				// remember what has to be cleaned up once the ManagedServiceAccount is gone
+				token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+				Expect(err).Should(BeNil())
+				serviceAccountNamespace, err := utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+				Expect(err).Should(BeNil())
+				Expect(utils.DoesManagedServiceAccountServiceAccountExist(
+					mcClient, serviceAccountNamespace, managedServiceAccountName)).Should(BeTrue())
+
+				//install managed-serviceaccount addon
+				err = utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+				Expect(err).Should(BeNil())
+
+				//eventually managed-serviceaccount addon to be deleted
+				Eventually(func() bool {
+					return utils.DoesManagedServiceAccountExist(hubClient, managedCluster, managedServiceAccountName)
+				}, time.Minute*10, time.Second*10).Should(BeFalse())
+
+				By("Waiting the ServiceAccount on the managed cluster to be removed")
+				Eventually(func() bool {
+					return utils.DoesManagedServiceAccountServiceAccountExist(
+						mcClient, serviceAccountNamespace, managedServiceAccountName)
+				}, time.Minute*2, time.Second*10).Should(BeFalse())
+
+				By("Waiting the token secret on the hub to be garbage collected")
+				Eventually(func() bool {
+					return utils.DoesManagedServiceAccountSecretExist(hubClient, managedCluster, secretName)
+				}, time.Minute*2, time.Second*10).Should(BeFalse())
+
+				By("Checking the old token is no longer authenticated")
+				Eventually(func() (bool, error) {
+					return utils.IsManagedServiceAccountTokenAuthenticated(mcClient, token)
+				}, time.Minute*2, time.Second*10).Should(BeFalse())
+			})
+		})
+	}
 
 	It("[P1][Sev1][cluster-lifecycle] able to disable managed-serviceaccount addon", func() {
+		if !addonInstalled {
+			Skip("ManagedServiceAccount addon was not installed by the suite")
+		}
+
 		//managed-serviceaccount addon shouldnt already be installed
 		managedServiceAccountAddon, err := utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
 		Expect(err).Should(BeNil())
@@ -163,4 +229,4 @@ var _ = Describe("e2e", Ordered, func() {
 			return utils.DoesManagedServiceAccountAddonExist(hubClient, managedCluster)
 		}, time.Minute*10, time.Second*10).Should(BeFalse())
 	})
-})
+}
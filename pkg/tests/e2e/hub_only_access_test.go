@@ -0,0 +1,112 @@
+package base_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed cluster access from the hub", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var bootstrapClient dynamic.Interface
+
+	gvrNamespace := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		if len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 {
+			Skip("ManagedCluster " + managedCluster.Name + " has no managedClusterClientConfigs to connect to")
+		}
+
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+	})
+
+	AfterAll(func() {
+		// in hub-only mode the specs that follow bootstrap the access again
+		Expect(utils.RemoveManagedClusterAccess(hubClient, managedCluster)).Should(Succeed())
+		Eventually(func() bool {
+			return utils.DoesManagedServiceAccountExist(hubClient, managedCluster, utils.BootstrapManagedServiceAccountName)
+		}, time.Minute*3, time.Second*10).Should(BeFalse())
+
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] able to reach the managed cluster with hub credentials only", func() {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*5)
+		defer cancel()
+
+		var err error
+		bootstrapClient, err = clients.GetManagedClusterDynamicClientFromHub(ctx, hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		Expect(utils.IsManifestWorkAvailable(hubClient, managedCluster, utils.BootstrapManagedServiceAccountName)).To(BeTrue())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] bootstrap client should trust the ManagedCluster caBundle on top of the secret ca.crt", func() {
+		secret, err := utils.GetManagedServiceAccountSecret(hubClient, managedCluster, utils.BootstrapManagedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		config, err := clients.GetManagedServiceAccountRestConfigWithClusterCABundle(hubClient, managedCluster, utils.BootstrapManagedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		expected := append([]byte{}, secret.Data["ca.crt"]...)
+		if caBundle := managedCluster.Spec.ManagedClusterClientConfigs[0].CABundle; len(caBundle) > 0 {
+			expected = append(append(expected, '\n'), caBundle...)
+		}
+		Expect(config.CAData).To(Equal(expected))
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] bootstrap client should reach the same cluster as the suite client", func() {
+		if options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+			Skip("in hub-only runs the suite client is the bootstrap client itself")
+		}
+
+		// the uid of kube-system identifies the cluster
+		viaToken, err := bootstrapClient.Resource(gvrNamespace).Get(context.TODO(), "kube-system", metav1.GetOptions{})
+		Expect(err).Should(BeNil())
+		viaSuite, err := mcClient.Resource(gvrNamespace).Get(context.TODO(), "kube-system", metav1.GetOptions{})
+		Expect(err).Should(BeNil())
+
+		Expect(viaToken.GetUID()).To(Equal(viaSuite.GetUID()))
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] bootstrap ManagedServiceAccount should be granted cluster-admin", func() {
+		review, err := utils.CreateSelfSubjectAccessReview(bootstrapClient, authorizationv1.ResourceAttributes{
+			Verb:     "*",
+			Group:    "*",
+			Resource: "*",
+		})
+		Expect(err).Should(BeNil())
+		Expect(review.Status.Allowed).To(BeTrue())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] removing the bootstrap access should revoke the token", func() {
+		Expect(utils.RemoveManagedClusterAccess(hubClient, managedCluster)).Should(Succeed())
+
+		Eventually(func() bool {
+			_, err := utils.CreateSelfSubjectAccessReview(bootstrapClient, authorizationv1.ResourceAttributes{
+				Namespace: "default",
+				Verb:      "list",
+				Resource:  "pods",
+			})
+			return errors.IsUnauthorized(err)
+		}, time.Minute*3, time.Second*10).Should(BeTrue())
+	})
+})
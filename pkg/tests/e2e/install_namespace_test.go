@@ -0,0 +1,120 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed-serviceaccount addon install namespace", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+
+	var installNamespace string
+	// addon that was installed before the specs in another namespace, it is recreated afterwards
+	var previousAddon *addonv1alpha1.ManagedClusterAddOn
+	var addonCreated bool
+	var managedServiceAccountName string
+
+	BeforeAll(func() {
+		skipWhenHubOnly("moving the addon to another install namespace")
+		hubClient, mcClient, managedCluster = setupClients()
+
+		installNamespace = options.TestOptions.Options.ManagedServiceAccount.InstallNamespace
+		if installNamespace == "" || installNamespace == utils.DefaultManagedServiceAccountInstallNamespace {
+			installNamespace = utils.DefaultManagedServiceAccountInstallNamespace + "-e2e"
+		}
+
+		err := utils.EnableManagedServiceAccountFeature(hubClient)
+		Expect(err).Should(BeNil(), "fail to enable the feature")
+
+		managedServiceAccountAddon, err := utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+		if err == nil {
+			previousInstallNamespace, err := utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+			Expect(err).Should(BeNil())
+
+			if previousInstallNamespace != installNamespace {
+				By("Removing the addon installed in " + previousInstallNamespace)
+				previousAddon = managedServiceAccountAddon
+				cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+				managedServiceAccountAddon = nil
+			}
+		} else {
+			Expect(errors.IsNotFound(err)).Should(BeTrue())
+		}
+
+		if managedServiceAccountAddon == nil {
+			_, err = utils.CreateManagedServiceAccountAddon(hubClient, managedCluster, installNamespace)
+			Expect(err).Should(BeNil())
+			addonCreated = true
+		}
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+
+		negotiateManagedServiceAccountVersions()
+	})
+
+	AfterAll(func() {
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+
+		if !addonCreated {
+			return
+		}
+		cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		if previousAddon != nil {
+			By("Recreating the addon installed in " + previousAddon.Spec.InstallNamespace)
+			_, err := utils.RecreateManagedServiceAccountAddon(hubClient, previousAddon)
+			Expect(err).Should(BeNil())
+			waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon status should report the requested install namespace", func() {
+		managedServiceAccountAddon, err := utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		Expect(managedServiceAccountAddon.Spec.InstallNamespace).To(Equal(installNamespace))
+		Expect(managedServiceAccountAddon.Status.Namespace).To(Equal(installNamespace))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] agent should be deployed in the install namespace", func() {
+		Eventually(func(g Gomega) {
+			deployment, err := utils.GetManagedServiceAccountAgentDeployment(hubClient, mcClient, managedCluster)
+			g.Expect(err).Should(BeNil())
+			g.Expect(deployment.Namespace).To(Equal(installNamespace))
+			g.Expect(deployment.Status.AvailableReplicas).To(BeNumerically(">", 0))
+		}, time.Minute*3, time.Second*10).Should(Succeed())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] ServiceAccount and token username should follow the install namespace", func() {
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-namespace-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		Expect(utils.DoesManagedServiceAccountServiceAccountExist(
+			mcClient, installNamespace, managedServiceAccountName)).Should(BeTrue())
+
+		username, err := utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(username).To(Equal("system:serviceaccount:" + installNamespace + ":" + managedServiceAccountName))
+
+		// the TokenReview on the managed cluster has the final word on the username
+		token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, token, username)).Should(BeTrue())
+	})
+})
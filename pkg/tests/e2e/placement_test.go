@@ -0,0 +1,172 @@
+package base_test
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libgooptions "github.com/stolostron/library-e2e-go/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed-serviceaccount addon placement", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var hubKubeClient kubernetes.Interface
+	var managedCluster *clusterv1.ManagedCluster
+
+	var uid string
+	var namespace string
+	placementName := "managed-serviceaccount"
+	labelKey := "e2e.managed-serviceaccount/placement"
+
+	// addon created by hand before the specs, it is recreated afterwards
+	var manualAddon *addonv1alpha1.ManagedClusterAddOn
+	var previousStrategy addonv1alpha1.InstallStrategy
+	var strategyReplaced bool
+
+	isPlaced := func() (bool, error) {
+		clusters, err := utils.ListPlacedManagedServiceAccountAddonClusters(hubClient)
+		return slices.Contains(clusters, managedCluster.Name), err
+	}
+	decisionClusters := func() ([]string, error) {
+		return utils.GetPlacementDecisionClusters(hubClient, namespace, placementName)
+	}
+	// the strategy holds only the placement of the specs and its label is uid-scoped,
+	// so the addons of the strategy are on exactly the clusters of the decisions
+	expectPlacedOnDecisions := func() {
+		Eventually(func(g Gomega) {
+			decisions, err := decisionClusters()
+			g.Expect(err).Should(BeNil())
+			placed, err := utils.ListPlacedManagedServiceAccountAddonClusters(hubClient)
+			g.Expect(err).Should(BeNil())
+			g.Expect(placed).To(ConsistOf(decisions))
+		}, time.Minute*5, time.Second*10).Should(Succeed())
+	}
+
+	BeforeAll(func() {
+		var err error
+		hubClient, _, managedCluster = setupClients()
+		hubKubeClient, err = clients.GetHubKubeClient()
+		Expect(err).Should(BeNil())
+
+		uid, err = libgooptions.GetUID()
+		Expect(err).Should(BeNil())
+
+		err = utils.EnableManagedServiceAccountFeature(hubClient)
+		Expect(err).Should(BeNil(), "fail to enable the feature")
+		var clusterManagementAddon *addonv1alpha1.ClusterManagementAddOn
+		Eventually(func() error {
+			clusterManagementAddon, err = utils.GetManagedServiceAccountClusterManagementAddon(hubClient)
+			return err
+		}, time.Minute*3, time.Second*10).Should(BeNil())
+
+		// replacing another strategy removes the addon from every cluster it placed
+		previousStrategy = clusterManagementAddon.Spec.InstallStrategy
+		if previousStrategy.Type != addonv1alpha1.AddonInstallStrategyManual &&
+			!options.TestOptions.Options.ManagedServiceAccount.ReplaceInstallStrategy {
+			Skip("the ClusterManagementAddOn install strategy is " + previousStrategy.Type +
+				", set replaceInstallStrategy in the options to let the placement specs replace it")
+		}
+
+		// an addon created by hand is not owned by the install strategy and would never be removed
+		addon, err := utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+		if err == nil {
+			placed, err := isPlaced()
+			Expect(err).Should(BeNil())
+			if !placed {
+				skipWhenHubOnly("removing the addon created by hand")
+				manualAddon = addon
+				cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+			}
+		} else {
+			Expect(errors.IsNotFound(err)).Should(BeTrue())
+		}
+
+		namespace = "e2e-msa-placement-" + uid
+		_, err = hubKubeClient.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		}, metav1.CreateOptions{})
+		Expect(err).Should(BeNil())
+
+		// the global set holds every cluster, the label decides which ones are selected
+		Expect(utils.CreateManagedClusterSetBinding(hubClient, namespace, "global")).Should(Succeed())
+
+		placement := utils.NewPlacement(namespace, placementName, map[string]string{labelKey: uid})
+		Expect(utils.CreatePlacement(hubClient, placement)).Should(Succeed())
+
+		// set before the strategy is replaced so it is restored whatever happens next
+		strategyReplaced = true
+		_, err = utils.SetManagedServiceAccountAddonInstallStrategy(
+			hubClient,
+			utils.NewPlacementsInstallStrategy(placement),
+		)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if managedCluster == nil {
+			return
+		}
+		// restored first, the strategy must not point at the placement of the namespace deleted below
+		if strategyReplaced {
+			_, err := utils.SetManagedServiceAccountAddonInstallStrategy(hubClient, previousStrategy)
+			Expect(err).Should(BeNil())
+		}
+		Expect(utils.RemoveManagedClusterLabel(hubClient, managedCluster.Name, labelKey)).Should(Succeed())
+
+		if namespace != "" {
+			err := hubKubeClient.CoreV1().Namespaces().Delete(context.TODO(), namespace, metav1.DeleteOptions{})
+			if !errors.IsNotFound(err) {
+				Expect(err).Should(BeNil())
+			}
+		}
+
+		if manualAddon != nil {
+			Eventually(func() bool {
+				return utils.DoesManagedServiceAccountAddonExist(hubClient, managedCluster)
+			}, time.Minute*10, time.Second*10).Should(BeFalse())
+
+			_, err := utils.RecreateManagedServiceAccountAddon(hubClient, manualAddon)
+			Expect(err).Should(BeNil())
+			waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should only be installed on clusters in the placement decisions", func() {
+		Eventually(decisionClusters, time.Minute*2, time.Second*10).Should(BeEmpty())
+
+		expectPlacedOnDecisions()
+		Expect(utils.DoesManagedServiceAccountAddonExist(hubClient, managedCluster)).Should(BeFalse())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should be installed once the cluster matches the placement", func() {
+		Expect(utils.SetManagedClusterLabel(hubClient, managedCluster.Name, labelKey, uid)).Should(Succeed())
+
+		Eventually(decisionClusters, time.Minute*2, time.Second*10).Should(ConsistOf(managedCluster.Name))
+
+		expectPlacedOnDecisions()
+		waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] addon should be removed once the cluster stops matching the placement", func() {
+		Expect(utils.RemoveManagedClusterLabel(hubClient, managedCluster.Name, labelKey)).Should(Succeed())
+
+		Eventually(decisionClusters, time.Minute*2, time.Second*10).Should(BeEmpty())
+
+		expectPlacedOnDecisions()
+		Eventually(func() bool {
+			return utils.DoesManagedServiceAccountAddonExist(hubClient, managedCluster)
+		}, time.Minute*10, time.Second*10).Should(BeFalse())
+	})
+})
@@ -0,0 +1,127 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount permissions", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var managedServiceAccountName string
+	var username string
+	var tokenClient dynamic.Interface
+	var workName string
+
+	namespace := "default"
+	rules := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"get", "list"},
+		},
+	}
+	granted := []utils.AccessCheck{
+		{ResourceAttributes: authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "list", Resource: "configmaps"}, Allowed: true},
+		{ResourceAttributes: authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "get", Resource: "configmaps", Name: "kube-root-ca.crt"}, Allowed: true},
+	}
+	denied := []utils.AccessCheck{
+		{ResourceAttributes: authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "delete", Resource: "configmaps"}, Allowed: false},
+		{ResourceAttributes: authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "list", Resource: "secrets"}, Allowed: false},
+		{ResourceAttributes: authorizationv1.ResourceAttributes{Namespace: "kube-system", Verb: "list", Resource: "configmaps"}, Allowed: false},
+	}
+	// with the binding gone every check is denied
+	revoked := []utils.AccessCheck{}
+	for _, check := range append(granted, denied...) {
+		check.Allowed = false
+		revoked = append(revoked, check)
+	}
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-rbac-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		username, err = utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		// SelfSubjectAccessReviews need to reach the managed cluster with the token
+		if len(managedCluster.Spec.ManagedClusterClientConfigs) > 0 {
+			tokenClient, err = clients.GetManagedServiceAccountDynamicClient(hubClient, managedCluster, managedServiceAccountName)
+			Expect(err).Should(BeNil())
+		}
+	})
+
+	AfterAll(func() {
+		if workName != "" {
+			err := utils.DeleteManifestWork(hubClient, managedCluster, workName)
+			if !errors.IsNotFound(err) {
+				Expect(err).Should(BeNil())
+			}
+		}
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] managed serviceaccount should have no permission before the grant", func() {
+		Expect(utils.VerifyAccessMatrix(mcClient, tokenClient, username, revoked)).Should(Succeed())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] permissions delivered by ManifestWork should match the access matrix", func() {
+		work, err := utils.GrantManagedServiceAccountPermissions(
+			hubClient,
+			managedCluster,
+			managedServiceAccountName,
+			namespace,
+			rules,
+		)
+		Expect(err).Should(BeNil())
+		workName = work.Name
+
+		Eventually(func() bool {
+			return utils.IsManifestWorkAvailable(hubClient, managedCluster, workName)
+		}, time.Minute*2, time.Second*10).Should(BeTrue())
+
+		Eventually(func() error {
+			return utils.VerifyAccessMatrix(mcClient, tokenClient, username, append(granted, denied...))
+		}, time.Minute*1, time.Second*5).Should(Succeed())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] permissions should be removed with the RoleBinding", func() {
+		Expect(utils.RemoveManagedServiceAccountRoleBinding(hubClient, managedCluster, workName)).Should(Succeed())
+
+		Eventually(func() bool {
+			return utils.IsManifestWorkAvailable(hubClient, managedCluster, workName)
+		}, time.Minute*2, time.Second*10).Should(BeTrue())
+
+		Eventually(func() error {
+			return utils.VerifyAccessMatrix(mcClient, tokenClient, username, revoked)
+		}, time.Minute*2, time.Second*5).Should(Succeed())
+	})
+})
@@ -0,0 +1,107 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount token rotation", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var validity time.Duration
+	var managedServiceAccountName string
+	var username string
+	var oldToken, newToken string
+	var oldExpiration metav1.Time
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		validity = options.TestOptions.Options.ManagedServiceAccount.RotationValidity.Duration
+		if validity == 0 {
+			validity = time.Minute * 10
+		}
+	})
+
+	AfterAll(func() {
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] able to create managed-serviceaccount with short validity", func() {
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-rotation-"),
+			utils.WithRotationValidity(validity),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		username, err = utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] token should be rotated before it expires", func() {
+		managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(managedServiceAccount.Status.TokenSecretRef).NotTo(BeNil())
+		Expect(managedServiceAccount.Status.ExpirationTimestamp).NotTo(BeNil())
+		oldRefresh := managedServiceAccount.Status.TokenSecretRef.LastRefreshTimestamp
+		oldExpiration = *managedServiceAccount.Status.ExpirationTimestamp
+
+		oldToken, err = utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, oldToken, username)).Should(BeTrue())
+
+		By("Waiting for the token in the hub secret to change")
+		Eventually(func(g Gomega) {
+			token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+			g.Expect(err).Should(BeNil())
+			g.Expect(token).NotTo(Equal(oldToken))
+
+			managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+			g.Expect(err).Should(BeNil())
+			g.Expect(managedServiceAccount.Status.TokenSecretRef.LastRefreshTimestamp.After(oldRefresh.Time)).To(BeTrue())
+			g.Expect(managedServiceAccount.Status.ExpirationTimestamp.After(oldExpiration.Time)).To(BeTrue())
+
+			newToken = token
+		}, validity, time.Second*15).Should(Succeed())
+
+		Expect(time.Now()).To(BeTemporally("<", oldExpiration.Time), "token was rotated after it expired")
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] old and new tokens should both be valid during the overlap", func() {
+		if time.Now().After(oldExpiration.Time) {
+			Skip("the old token already expired, there is no overlap window left to check")
+		}
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, oldToken, username)).Should(BeTrue())
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, newToken, username)).Should(BeTrue())
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] old token should be rejected once expired", func() {
+		Eventually(func() (bool, error) {
+			return utils.IsManagedServiceAccountTokenAuthenticated(mcClient, oldToken)
+		}, time.Until(oldExpiration.Time)+time.Minute*2, time.Second*15).Should(BeFalse())
+
+		Expect(utils.ValidateManagedServiceAccountToken(mcClient, newToken, username)).Should(BeTrue())
+	})
+})
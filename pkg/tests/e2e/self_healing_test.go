@@ -0,0 +1,123 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount self-healing", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var repairTimeout time.Duration
+	var managedServiceAccountName string
+	var username string
+	var serviceAccountNamespace string
+
+	// currentSecretName returns the token secret referenced by the status right now
+	currentSecretName := func() string {
+		managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(managedServiceAccount.Status.TokenSecretRef).NotTo(BeNil())
+		return managedServiceAccount.Status.TokenSecretRef.Name
+	}
+
+	// expectRepaired waits for the conditions to be back and the hub secret to hold a working token,
+	// a timeout on the conditions reports them
+	expectRepaired := func(brokenToken string) {
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, repairTimeout)
+
+		Eventually(func(g Gomega) {
+			token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+			g.Expect(err).Should(BeNil())
+			g.Expect(token).NotTo(Equal(brokenToken))
+
+			valid, err := utils.ValidateManagedServiceAccountToken(mcClient, token, username)
+			g.Expect(err).Should(BeNil())
+			g.Expect(valid).To(BeTrue())
+		}, repairTimeout, time.Second*10).Should(Succeed())
+	}
+
+	BeforeAll(func() {
+		hubClient, mcClient, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		repairTimeout = options.TestOptions.Options.ManagedServiceAccount.RepairTimeout.Duration
+		if repairTimeout == 0 {
+			repairTimeout = time.Minute * 5
+		}
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-healing-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		username, err = utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		serviceAccountNamespace, err = utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] deleted token secret should be recreated", func() {
+		secretName := currentSecretName()
+		Expect(utils.DeleteManagedServiceAccountSecret(hubClient, managedCluster, secretName)).Should(Succeed())
+
+		// only a secret read back without error counts as recreated
+		Eventually(func(g Gomega) {
+			secret, err := utils.GetManagedServiceAccountSecret(hubClient, managedCluster, managedServiceAccountName)
+			g.Expect(err).Should(BeNil())
+			g.Expect(secret.Name).To(Equal(secretName))
+			g.Expect(secret.DeletionTimestamp).To(BeNil())
+		}, repairTimeout, time.Second*10).Should(Succeed())
+
+		// the recreated secret may carry the same still valid token, only a broken token has to change
+		expectRepaired("")
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] tampered token should be overwritten", func() {
+		garbage := "e2e-garbage-token"
+		Expect(utils.SetManagedServiceAccountSecretToken(
+			hubClient, managedCluster, currentSecretName(), []byte(garbage))).Should(Succeed())
+
+		expectRepaired(garbage)
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] deleted ServiceAccount should be recreated with a new token", func() {
+		token, err := utils.GetManagedServiceAccountToken(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		Expect(utils.DeleteManagedServiceAccountServiceAccount(
+			mcClient, serviceAccountNamespace, managedServiceAccountName)).Should(Succeed())
+
+		Eventually(func() error {
+			_, err := utils.GetManagedServiceAccountServiceAccount(mcClient, serviceAccountNamespace, managedServiceAccountName)
+			return err
+		}, repairTimeout, time.Second*10).Should(Succeed())
+
+		// tokens are bound to the uid of the deleted ServiceAccount, so a new one has to be issued
+		expectRepaired(token)
+	})
+})
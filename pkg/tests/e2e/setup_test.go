@@ -0,0 +1,295 @@
+package base_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libgocmd "github.com/stolostron/library-e2e-go/pkg/cmd"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/options"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// setupClients loads the options and returns the hub client, the managed cluster
+// client and the managed cluster the specs run against
+func setupClients() (dynamic.Interface, dynamic.Interface, *clusterv1.ManagedCluster) {
+	return setupClientsFor()
+}
+
+// setupClientsFor is setupClients choosing among the named clusters instead of all the clusters of the options
+func setupClientsFor(clusterNames ...string) (dynamic.Interface, dynamic.Interface, *clusterv1.ManagedCluster) {
+	//initialize options
+	err := options.LoadOptions(libgocmd.End2End.OptionsFile)
+	Expect(err).To(BeNil())
+
+	//initialize hub dynamic client
+	hubClient, err := clients.GetHubDynamicClient()
+	Expect(err).Should(BeNil())
+	selectFeatureInstaller(hubClient)
+
+	//find a managed cluster to do the test on
+	selector := clusterSelector()
+	if len(clusterNames) > 0 {
+		selector.Names = clusterNames
+	}
+	managedCluster, err := utils.SelectManagedCluster(hubClient, selector)
+	Expect(err).Should(BeNil())
+
+	//initialize managedcluster dynamic client
+	if options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+		return hubClient, bootstrapManagedClusterClient(hubClient, managedCluster), managedCluster
+	}
+	mcClient, err := clients.GetManagedClusterDynamicClient(managedCluster.Name)
+	Expect(err).Should(BeNil())
+
+	return hubClient, mcClient, managedCluster
+}
+
+// fanOutClusterNames returns the clusters of the options when the lifecycle fans out across them, nil otherwise.
+// it is called while the spec tree is built, before any spec has loaded the options, so the error is
+// returned for a spec to report rather than failing outside of any spec
+func fanOutClusterNames() ([]string, error) {
+	if err := options.LoadOptions(libgocmd.End2End.OptionsFile); err != nil {
+		return nil, err
+	}
+	if !options.TestOptions.Options.ManagedServiceAccount.FanOut {
+		return nil, nil
+	}
+	return utils.OptionsClusterNames(), nil
+}
+
+// printEffectiveOptions shows the options the suite runs with once every layer is applied, secrets redacted.
+// a report entry is printed by every reporter and kept in the reports, unlike the GinkgoWriter output of a passing node
+func printEffectiveOptions() {
+	err := options.LoadOptions(libgocmd.End2End.OptionsFile)
+	Expect(err).To(BeNil())
+
+	var effectiveOptions strings.Builder
+	Expect(options.PrintOptions(&effectiveOptions)).Should(Succeed())
+	AddReportEntry("effective options", effectiveOptions.String())
+}
+
+// probeClusterCredentials checks every cluster of the options is reachable with its credentials
+// before any spec runs, hub-only runs need no cluster credentials
+func probeClusterCredentials() {
+	if options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+		return
+	}
+
+	registry, err := clients.NewClusterRegistryFromOptions()
+	Expect(err).Should(BeNil(), "invalid cluster credentials in the options")
+	Expect(registry.ProbeAll()).Should(Succeed())
+}
+
+// bootstrapManagedClusterClient reaches the managed cluster with a cluster-admin ManagedServiceAccount.
+// the feature and the addon are set up first and left in place, the suite snapshot puts them back
+func bootstrapManagedClusterClient(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) dynamic.Interface {
+	err := utils.EnableManagedServiceAccountFeature(hubClient)
+	Expect(err).Should(BeNil(), "fail to enable the feature")
+
+	_, err = utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+	if errors.IsNotFound(err) {
+		_, err = utils.CreateManagedServiceAccountAddon(
+			hubClient,
+			managedCluster,
+			options.TestOptions.Options.ManagedServiceAccount.InstallNamespace,
+		)
+	}
+	Expect(err).Should(BeNil())
+	waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute*5)
+	defer cancel()
+
+	mcClient, err := clients.GetManagedClusterDynamicClientFromHub(ctx, hubClient, managedCluster)
+	Expect(err).Should(BeNil())
+	return mcClient
+}
+
+// removeManagedClusterAccess deletes what bootstrapManagedClusterClient set up on any cluster
+func removeManagedClusterAccess() {
+	if !options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+		return
+	}
+
+	hubClient, err := clients.GetHubDynamicClient()
+	Expect(err).Should(BeNil())
+
+	Expect(utils.RemoveAllManagedClusterAccess(hubClient)).Should(Succeed())
+}
+
+// skipWhenHubOnly skips specs that remove the addon of the managed cluster, in hub-only mode its
+// agent and install namespace back the token every managed cluster client authenticates with
+func skipWhenHubOnly(what string) {
+	err := options.LoadOptions(libgocmd.End2End.OptionsFile)
+	Expect(err).To(BeNil())
+
+	if options.TestOptions.Options.ManagedServiceAccount.HubOnly {
+		Skip(what + " would revoke the token the hub-only managed cluster client uses")
+	}
+}
+
+// clusterSelector selects among the clusters of the options with the selection options
+func clusterSelector() utils.ClusterSelector {
+	selection := options.TestOptions.Options.ManagedServiceAccount.ClusterSelection
+	return utils.ClusterSelector{
+		Names:                utils.OptionsClusterNames(),
+		LabelSelector:        selection.LabelSelector,
+		Claims:               selection.Claims,
+		MinKubernetesVersion: selection.MinKubernetesVersion,
+	}
+}
+
+// selectFeatureInstaller picks how the feature is enabled on the hub from the options
+func selectFeatureInstaller(hubClient dynamic.Interface) {
+	installer, err := utils.NewFeatureInstaller(
+		hubClient,
+		options.TestOptions.Options.ManagedServiceAccount.InstallMode,
+		options.TestOptions.Options.ManagedServiceAccount.UpstreamImage,
+	)
+	Expect(err).Should(BeNil())
+	utils.SetFeatureInstaller(installer)
+}
+
+// negotiateManagedServiceAccountVersions returns the ManagedServiceAccount versions selected
+// by the options, the ManagedServiceAccount api is only served once the feature is enabled
+func negotiateManagedServiceAccountVersions() []string {
+	hubKubeClient, err := clients.GetHubKubeClient()
+	Expect(err).Should(BeNil())
+
+	var versions []string
+	Eventually(func() error {
+		versions, err = utils.NegotiateManagedServiceAccountVersions(
+			hubKubeClient.Discovery(),
+			options.TestOptions.Options.ManagedServiceAccount.APIVersion,
+		)
+		return err
+	}, time.Minute*1, time.Second*10).Should(BeNil())
+
+	return versions
+}
+
+// waitForManagedServiceAccountReady fails the spec with the last seen conditions
+// when the ManagedServiceAccount is not ready in time
+func waitForManagedServiceAccountReady(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+	timeout time.Duration,
+) {
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	_, err := utils.WaitForManagedServiceAccountReady(ctx, hubClient, managedCluster, name)
+	ExpectWithOffset(1, err).ShouldNot(HaveOccurred())
+}
+
+// deferDeleteManagedServiceAccount deletes the ManagedServiceAccount once the spec or the container it is
+// created in ends, a failed assertion does not leak it on the hub. it may already be gone, as with a ttl
+func deferDeleteManagedServiceAccount(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) {
+	DeferCleanup(func() {
+		err := utils.DeleteManagedServiceAccount(hubClient, managedCluster, name)
+		if !errors.IsNotFound(err) {
+			Expect(err).Should(BeNil())
+		}
+	})
+}
+
+// waitForAddonAvailable fails the spec with the last seen conditions
+// when the managed-serviceaccount addon is not available in time
+func waitForAddonAvailable(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	timeout time.Duration,
+) {
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	_, err := utils.WaitForAddonAvailable(ctx, hubClient, managedCluster)
+	ExpectWithOffset(1, err).ShouldNot(HaveOccurred())
+}
+
+// prepareManagedServiceAccountAddon enables the feature and installs the addon when it is missing,
+// so a spec family does not depend on the lifecycle specs running first.
+// returns true when the addon was created and should be removed afterwards
+func prepareManagedServiceAccountAddon(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) bool {
+	err := utils.EnableManagedServiceAccountFeature(hubClient)
+	Expect(err).Should(BeNil(), "fail to enable the feature")
+
+	created := false
+	_, err = utils.GetManagedServiceAccountAddon(hubClient, managedCluster)
+	if errors.IsNotFound(err) {
+		_, err = utils.CreateManagedServiceAccountAddon(
+			hubClient,
+			managedCluster,
+			options.TestOptions.Options.ManagedServiceAccount.InstallNamespace,
+		)
+		Expect(err).Should(BeNil())
+		created = true
+	} else {
+		Expect(err).Should(BeNil())
+	}
+
+	waitForAddonAvailable(hubClient, managedCluster, time.Minute*10)
+
+	return created
+}
+
+// cleanupManagedServiceAccountAddon removes the addon installed by prepareManagedServiceAccountAddon
+func cleanupManagedServiceAccountAddon(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) {
+	err := utils.DeleteManagedServiceAccountAddon(hubClient, managedCluster)
+	if !errors.IsNotFound(err) {
+		Expect(err).Should(BeNil())
+	}
+
+	Eventually(func() bool {
+		return utils.DoesManagedServiceAccountAddonExist(hubClient, managedCluster)
+	}, time.Minute*10, time.Second*10).Should(BeFalse())
+}
+
+// hubSnapshot is the hub state before the suite, restored after it
+var hubSnapshot *utils.ManagedServiceAccountSnapshot
+
+// snapshotManagedServiceAccountState records the MCE component entry, or whether the feature is enabled, and the addons
+// so a shared environment is left the way it was found
+func snapshotManagedServiceAccountState() {
+	err := options.LoadOptions(libgocmd.End2End.OptionsFile)
+	Expect(err).To(BeNil())
+
+	hubClient, err := clients.GetHubDynamicClient()
+	Expect(err).Should(BeNil())
+	selectFeatureInstaller(hubClient)
+
+	hubSnapshot, err = utils.SnapshotManagedServiceAccountState(hubClient)
+	Expect(err).Should(BeNil())
+}
+
+func restoreManagedServiceAccountState() {
+	if hubSnapshot == nil {
+		return
+	}
+
+	hubClient, err := clients.GetHubDynamicClient()
+	Expect(err).Should(BeNil())
+
+	Expect(utils.RestoreManagedServiceAccountState(hubClient, hubSnapshot)).Should(Succeed())
+}
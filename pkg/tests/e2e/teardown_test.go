@@ -0,0 +1,105 @@
+package base_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("managed-serviceaccount feature teardown", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var mcClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+
+	var agentNamespace string
+	var managedServiceAccountName string
+
+	BeforeAll(func() {
+		skipWhenHubOnly("disabling the feature")
+		hubClient, mcClient, managedCluster = setupClients()
+		prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		var err error
+		agentNamespace, err = utils.GetManagedServiceAccountNamespace(hubClient, managedCluster)
+		Expect(err).Should(BeNil())
+		_, err = utils.GetManagedServiceAccountAgentDeploymentInNamespace(mcClient, agentNamespace)
+		Expect(err).Should(BeNil())
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-teardown-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+	})
+
+	AfterAll(func() {
+		if hubClient == nil {
+			return
+		}
+		// the feature is needed again for the specs that follow, the addons come back with the suite snapshot
+		err := utils.EnableManagedServiceAccountFeature(hubClient)
+		Expect(err).Should(BeNil(), "fail to enable the feature")
+		Eventually(func() error {
+			_, err := utils.GetManagedServiceAccountClusterManagementAddon(hubClient)
+			return err
+		}, time.Minute*3, time.Second*10).Should(BeNil())
+
+		if managedServiceAccountName != "" {
+			err := utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+			if !errors.IsNotFound(err) {
+				Expect(err).Should(BeNil())
+			}
+			Eventually(func() bool {
+				return utils.DoesManagedServiceAccountExist(hubClient, managedCluster, managedServiceAccountName)
+			}, time.Minute*3, time.Second*10).Should(BeFalse())
+		}
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] able to disable managed-serviceaccount feature on hub", func() {
+		By("Disabling ManagedServiceAccount feature")
+		Expect(utils.DisableManagedServiceAccountFeature(hubClient)).Should(Succeed())
+
+		enabled, err := utils.IsManagedServiceAccountFeatureEnabled(hubClient)
+		Expect(err).Should(BeNil())
+		Expect(enabled).To(BeFalse())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] ClusterManagementAddon managed-serviceaccount should be removed", func() {
+		Eventually(func() bool {
+			_, err := utils.GetManagedServiceAccountClusterManagementAddon(hubClient)
+			return errors.IsNotFound(err)
+		}, time.Minute*5, time.Second*10).Should(BeTrue())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] managed-serviceaccount addons should be removed from every cluster", func() {
+		Eventually(func() ([]string, error) {
+			return utils.ListManagedServiceAccountAddonClusters(hubClient)
+		}, time.Minute*10, time.Second*10).Should(BeEmpty())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] managed-serviceaccount agent should be removed from the managed cluster", func() {
+		Eventually(func() bool {
+			_, err := utils.GetManagedServiceAccountAgentDeploymentInNamespace(mcClient, agentNamespace)
+			return errors.IsNotFound(err)
+		}, time.Minute*10, time.Second*10).Should(BeTrue())
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] ManagedServiceAccount CRD and resources should be left behind", func() {
+		// disabling the component stops the addon, it does not delete the api or the data stored with it
+		Expect(utils.DoesManagedServiceAccountCRDExist(hubClient)).To(BeTrue())
+
+		managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(managedServiceAccount.DeletionTimestamp).To(BeNil())
+	})
+})
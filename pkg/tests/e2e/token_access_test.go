@@ -0,0 +1,109 @@
+package base_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/clients"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount token access", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var managedServiceAccountName string
+	var username string
+	var tokenConfig *rest.Config
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+		if len(managedCluster.Spec.ManagedClusterClientConfigs) == 0 {
+			Skip("ManagedCluster " + managedCluster.Name + " has no managedClusterClientConfigs to connect to")
+		}
+
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-access-"),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		username, err = utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] able to build a client from the token secret and the cluster url", func() {
+		var err error
+		tokenConfig, err = clients.GetManagedServiceAccountRestConfig(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(tokenConfig.Host).To(Equal(managedCluster.Spec.ManagedClusterClientConfigs[0].URL))
+
+		// only the ca.crt of the secret is trusted, so the TLS specs below check that it works
+		secret, err := utils.GetManagedServiceAccountSecret(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(tokenConfig.CAData).NotTo(BeEmpty())
+		Expect(tokenConfig.CAData).To(Equal(secret.Data["ca.crt"]))
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] token should authenticate api calls over the managed cluster TLS endpoint", func() {
+		tokenClient, err := dynamic.NewForConfig(tokenConfig)
+		Expect(err).Should(BeNil())
+
+		// any authenticated user can create a SelfSubjectAccessReview
+		review, err := utils.CreateSelfSubjectAccessReview(tokenClient, authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      "list",
+			Resource:  "pods",
+		})
+		Expect(err).Should(BeNil())
+		Expect(review.Status.Allowed).To(BeFalse(), "a fresh ManagedServiceAccount should not be granted anything")
+
+		kubeClient, err := clients.GetManagedServiceAccountKubeClient(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+
+		// the apiserver identifies the token owner in the forbidden error
+		_, err = kubeClient.CoreV1().Secrets("kube-system").List(context.TODO(), metav1.ListOptions{})
+		Expect(errors.IsForbidden(err)).To(BeTrue(), "expecting forbidden, got %v", err)
+		Expect(err.Error()).To(ContainSubstring(username))
+	})
+
+	It("[P1][Sev1][cluster-lifecycle] invalid token should be rejected by the managed cluster", func() {
+		invalidConfig := rest.CopyConfig(tokenConfig)
+		invalidConfig.BearerToken = "invalid"
+
+		invalidClient, err := dynamic.NewForConfig(invalidConfig)
+		Expect(err).Should(BeNil())
+
+		_, err = utils.CreateSelfSubjectAccessReview(invalidClient, authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      "list",
+			Resource:  "pods",
+		})
+		Expect(errors.IsUnauthorized(err)).To(BeTrue(), "expecting unauthorized, got %v", err)
+	})
+})
@@ -0,0 +1,79 @@
+package base_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stolostron/managed-serviceaccount-e2e/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var _ = Describe("ManagedServiceAccount token claims", Ordered, Serial, func() {
+	var hubClient dynamic.Interface
+	var managedCluster *clusterv1.ManagedCluster
+	var addonCreated bool
+
+	var managedServiceAccountName string
+	var claims *utils.TokenClaims
+
+	// claims are compared to timestamps written by other components, allow for some clock skew
+	tolerance := time.Minute * 1
+	validity := time.Hour * 2
+
+	BeforeAll(func() {
+		hubClient, _, managedCluster = setupClients()
+		addonCreated = prepareManagedServiceAccountAddon(hubClient, managedCluster)
+		negotiateManagedServiceAccountVersions()
+
+		createdManagedServiceAccount, err := utils.CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			utils.WithGenerateName("e2e-claims-"),
+			utils.WithRotationValidity(validity),
+		)
+		Expect(err).Should(BeNil())
+		managedServiceAccountName = createdManagedServiceAccount.Name
+
+		waitForManagedServiceAccountReady(hubClient, managedCluster, managedServiceAccountName, time.Minute*1)
+
+		claims, err = utils.GetManagedServiceAccountTokenClaims(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterAll(func() {
+		if managedServiceAccountName != "" {
+			Expect(utils.DeleteManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)).Should(Succeed())
+		}
+		if addonCreated {
+			cleanupManagedServiceAccountAddon(hubClient, managedCluster)
+		}
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] token should be a bound, time-limited token", func() {
+		Expect(claims.IsBoundToken()).To(BeTrue(), "token looks like a legacy secret based token: %+v", claims)
+		Expect(claims.Issuer).NotTo(BeEmpty())
+		Expect(claims.Audience).NotTo(BeEmpty())
+		Expect(claims.ServiceAccountName()).To(Equal(managedServiceAccountName))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] token subject should match the managed serviceaccount username", func() {
+		username, err := utils.GetManagedServiceAccountUserName(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(claims.Subject).To(Equal(username))
+		Expect(username).To(HavePrefix("system:serviceaccount:" + claims.ServiceAccountNamespace() + ":"))
+		Expect(strings.TrimPrefix(username, "system:serviceaccount:"+claims.ServiceAccountNamespace()+":")).
+			To(Equal(claims.ServiceAccountName()))
+	})
+
+	It("[P2][Sev2][cluster-lifecycle] token expiration should match the status and the rotation validity", func() {
+		managedServiceAccount, err := utils.GetManagedServiceAccount(hubClient, managedCluster, managedServiceAccountName)
+		Expect(err).Should(BeNil())
+		Expect(managedServiceAccount.Status.ExpirationTimestamp).NotTo(BeNil())
+
+		Expect(claims.ExpirationTime()).To(BeTemporally("~", managedServiceAccount.Status.ExpirationTimestamp.Time, tolerance))
+		Expect(claims.ExpirationTime().Sub(claims.IssuedAtTime())).To(BeNumerically("~", validity, tolerance))
+	})
+})
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+func unstructuredToSelfSubjectAccessReview(
+	u *unstructured.Unstructured,
+) (*authorizationv1.SelfSubjectAccessReview, error) {
+	ssar := &authorizationv1.SelfSubjectAccessReview{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		ssar,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ssar, nil
+}
+
+// CreateSelfSubjectAccessReview asks the apiserver whether the identity behind the client
+// is allowed to do the given action, it needs nothing but an authenticated client
+func CreateSelfSubjectAccessReview(
+	client dynamic.Interface,
+	attributes authorizationv1.ResourceAttributes,
+) (*authorizationv1.SelfSubjectAccessReview, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "authorization.k8s.io",
+		Version:  "v1",
+		Resource: "selfsubjectaccessreviews",
+	}
+
+	newSelfSubjectAccessReview := &authorizationv1.SelfSubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "SelfSubjectAccessReview",
+			APIVersion: "authorization.k8s.io/v1",
+		},
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+		},
+	}
+
+	uNewSelfSubjectAccessReview, err := toUnstructured(newSelfSubjectAccessReview)
+	if err != nil {
+		return nil, err
+	}
+
+	uSelfSubjectAccessReview, err := client.Resource(gvr).Create(
+		context.TODO(),
+		uNewSelfSubjectAccessReview,
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToSelfSubjectAccessReview(uSelfSubjectAccessReview)
+}
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ManagedServiceAccountAgentDeploymentName is the agent Deployment the addon installs on the managed cluster
+const ManagedServiceAccountAgentDeploymentName = "managed-serviceaccount-addon-agent"
+
+var gvrAddOnDeploymentConfig = schema.GroupVersionResource{
+	Group:    "addon.open-cluster-management.io",
+	Version:  "v1alpha1",
+	Resource: "addondeploymentconfigs",
+}
+
+// addOnDeploymentConfigGroupResource is how addons reference an AddOnDeploymentConfig in spec.configs
+var addOnDeploymentConfigGroupResource = addonv1alpha1.ConfigGroupResource{
+	Group:    gvrAddOnDeploymentConfig.Group,
+	Resource: gvrAddOnDeploymentConfig.Resource,
+}
+
+func unstructuredToAddOnDeploymentConfig(
+	u *unstructured.Unstructured,
+) (*addonv1alpha1.AddOnDeploymentConfig, error) {
+	config := &addonv1alpha1.AddOnDeploymentConfig{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		config,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func GetAddOnDeploymentConfig(
+	hubClient dynamic.Interface,
+	namespace string,
+	name string,
+) (*addonv1alpha1.AddOnDeploymentConfig, error) {
+	uConfig, err := hubClient.Resource(gvrAddOnDeploymentConfig).
+		Namespace(namespace).
+		Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToAddOnDeploymentConfig(uConfig)
+}
+
+func CreateAddOnDeploymentConfig(
+	hubClient dynamic.Interface,
+	namespace string,
+	name string,
+	spec addonv1alpha1.AddOnDeploymentConfigSpec,
+) (*addonv1alpha1.AddOnDeploymentConfig, error) {
+	config := &addonv1alpha1.AddOnDeploymentConfig{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AddOnDeploymentConfig",
+			APIVersion: "addon.open-cluster-management.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: spec,
+	}
+
+	uConfig, err := toUnstructured(config)
+	if err != nil {
+		return nil, err
+	}
+
+	uCreatedConfig, err := hubClient.Resource(gvrAddOnDeploymentConfig).
+		Namespace(namespace).
+		Create(context.TODO(), uConfig, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToAddOnDeploymentConfig(uCreatedConfig)
+}
+
+func DeleteAddOnDeploymentConfig(
+	hubClient dynamic.Interface,
+	namespace string,
+	name string,
+) error {
+	return hubClient.Resource(gvrAddOnDeploymentConfig).
+		Namespace(namespace).
+		Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// SetAddOnDeploymentConfigCustomizedVariables replaces the customized variables of the AddOnDeploymentConfig
+func SetAddOnDeploymentConfigCustomizedVariables(
+	hubClient dynamic.Interface,
+	namespace string,
+	name string,
+	variables []addonv1alpha1.CustomizedVariable,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		config, err := GetAddOnDeploymentConfig(hubClient, namespace, name)
+		if err != nil {
+			return err
+		}
+		config.Spec.CustomizedVariables = variables
+
+		uConfig, err := toUnstructured(config)
+		if err != nil {
+			return err
+		}
+
+		_, err = hubClient.Resource(gvrAddOnDeploymentConfig).
+			Namespace(namespace).
+			Update(context.TODO(), uConfig, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// SetManagedServiceAccountAddonDeploymentConfig points the managed-serviceaccount ManagedClusterAddOn
+// at the AddOnDeploymentConfig, a nil config removes the reference so the default applies again.
+// the other configs of the addon are kept
+func SetManagedServiceAccountAddonDeploymentConfig(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	config *addonv1alpha1.AddOnDeploymentConfig,
+) error {
+	return updateManagedServiceAccountAddonConfigs(hubClient, managedCluster, func(current []addonv1alpha1.AddOnConfig) []addonv1alpha1.AddOnConfig {
+		configs := []addonv1alpha1.AddOnConfig{}
+		for _, c := range current {
+			if c.ConfigGroupResource != addOnDeploymentConfigGroupResource {
+				configs = append(configs, c)
+			}
+		}
+		if config != nil {
+			configs = append(configs, addonv1alpha1.AddOnConfig{
+				ConfigGroupResource: addOnDeploymentConfigGroupResource,
+				ConfigReferent: addonv1alpha1.ConfigReferent{
+					Namespace: config.Namespace,
+					Name:      config.Name,
+				},
+			})
+		}
+		return configs
+	})
+}
+
+// SetManagedServiceAccountAddonConfigs replaces every config of the managed-serviceaccount
+// ManagedClusterAddOn, to put back the configs read before a spec changed them
+func SetManagedServiceAccountAddonConfigs(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	configs []addonv1alpha1.AddOnConfig,
+) error {
+	return updateManagedServiceAccountAddonConfigs(hubClient, managedCluster, func([]addonv1alpha1.AddOnConfig) []addonv1alpha1.AddOnConfig {
+		return configs
+	})
+}
+
+func updateManagedServiceAccountAddonConfigs(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	update func([]addonv1alpha1.AddOnConfig) []addonv1alpha1.AddOnConfig,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
+		if err != nil {
+			return err
+		}
+		managedServiceAccountAddon.Spec.Configs = update(managedServiceAccountAddon.Spec.Configs)
+
+		uManagedServiceAccountAddon, err := toUnstructured(managedServiceAccountAddon)
+		if err != nil {
+			return err
+		}
+
+		_, err = hubClient.Resource(gvrManagedClusterAddon).
+			Namespace(managedCluster.Name).
+			Update(context.TODO(), uManagedServiceAccountAddon, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// SetManagedServiceAccountAddonDefaultDeploymentConfig sets the AddOnDeploymentConfig the
+// managed-serviceaccount ClusterManagementAddOn hands to every addon without its own config,
+// a nil config clears the default. returns the default that was replaced, nil if there was none
+func SetManagedServiceAccountAddonDefaultDeploymentConfig(
+	hubClient dynamic.Interface,
+	config *addonv1alpha1.ConfigReferent,
+) (*addonv1alpha1.ConfigReferent, error) {
+	var previous *addonv1alpha1.ConfigReferent
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterManagementAddon, err := GetManagedServiceAccountClusterManagementAddon(hubClient)
+		if err != nil {
+			return err
+		}
+
+		previous = nil
+		idx := -1
+		for i, c := range clusterManagementAddon.Spec.SupportedConfigs {
+			if c.ConfigGroupResource == addOnDeploymentConfigGroupResource {
+				idx = i
+				previous = c.DefaultConfig
+			}
+		}
+		if idx < 0 {
+			clusterManagementAddon.Spec.SupportedConfigs = append(
+				clusterManagementAddon.Spec.SupportedConfigs,
+				addonv1alpha1.ConfigMeta{ConfigGroupResource: addOnDeploymentConfigGroupResource},
+			)
+			idx = len(clusterManagementAddon.Spec.SupportedConfigs) - 1
+		}
+		clusterManagementAddon.Spec.SupportedConfigs[idx].DefaultConfig = config
+
+		uClusterManagementAddon, err := toUnstructured(clusterManagementAddon)
+		if err != nil {
+			return err
+		}
+
+		_, err = hubClient.Resource(gvrClusterManagementAddon).
+			Update(context.TODO(), uClusterManagementAddon, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+// GetAddOnDeploymentConfigReference returns the status.configReferences entry of the managed-serviceaccount
+// addon for its AddOnDeploymentConfig, nil when the addon reports none. an error is returned when it
+// reports another AddOnDeploymentConfig
+func GetAddOnDeploymentConfigReference(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	namespace string,
+	name string,
+) (*addonv1alpha1.ConfigReference, error) {
+	managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, ref := range managedServiceAccountAddon.Status.ConfigReferences {
+		if ref.ConfigGroupResource != addOnDeploymentConfigGroupResource {
+			continue
+		}
+		if ref.Namespace != namespace || ref.Name != name {
+			return nil, fmt.Errorf("addon references AddOnDeploymentConfig %s/%s instead of %s/%s",
+				ref.Namespace, ref.Name, namespace, name)
+		}
+		return &managedServiceAccountAddon.Status.ConfigReferences[i], nil
+	}
+
+	return nil, nil
+}
+
+// IsAddOnDeploymentConfigApplied checks the managed-serviceaccount addon reports the config as
+// its current AddOnDeploymentConfig and the agent has been rendered with its latest spec
+func IsAddOnDeploymentConfigApplied(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	namespace string,
+	name string,
+) (bool, error) {
+	ref, err := GetAddOnDeploymentConfigReference(hubClient, managedCluster, namespace, name)
+	if err != nil || ref == nil {
+		return false, err
+	}
+	if ref.DesiredConfig == nil || ref.LastAppliedConfig == nil {
+		return false, nil
+	}
+	return ref.DesiredConfig.SpecHash != "" &&
+		ref.DesiredConfig.SpecHash == ref.LastAppliedConfig.SpecHash, nil
+}
+
+func unstructuredToDeployment(
+	u *unstructured.Unstructured,
+) (*appsv1.Deployment, error) {
+	deployment := &appsv1.Deployment{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		deployment,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// GetManagedServiceAccountAgentDeployment gets the agent Deployment from the addon install namespace
+func GetManagedServiceAccountAgentDeployment(
+	hubClient dynamic.Interface,
+	mcDynClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) (*appsv1.Deployment, error) {
+	namespace, err := GetManagedServiceAccountNamespace(hubClient, managedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetManagedServiceAccountAgentDeploymentInNamespace(mcDynClient, namespace)
+}
+
+// GetManagedServiceAccountAgentDeploymentInNamespace gets the agent Deployment without asking the hub
+// where it is installed, for when the addon is already gone
+func GetManagedServiceAccountAgentDeploymentInNamespace(
+	mcDynClient dynamic.Interface,
+	namespace string,
+) (*appsv1.Deployment, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "apps",
+		Version:  "v1",
+		Resource: "deployments",
+	}
+
+	uDeployment, err := mcDynClient.
+		Resource(gvr).
+		Namespace(namespace).
+		Get(context.TODO(), ManagedServiceAccountAgentDeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToDeployment(uDeployment)
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// AddonConditionTypes are the ManagedClusterAddOn conditions a health report always lists,
+// a missing one is reported as such instead of being left out
+var AddonConditionTypes = []string{
+	addonv1alpha1.ManagedClusterAddOnConditionAvailable,
+	addonv1alpha1.ManagedClusterAddOnConditionDegraded,
+	addonv1alpha1.ManagedClusterAddOnConditionProgressing,
+	addonv1alpha1.ManagedClusterAddOnConditionConfigured,
+	addonv1alpha1.ManagedClusterAddOnManifestApplied,
+	addonv1alpha1.ManagedClusterAddOnRegistrationApplied,
+}
+
+// AddonHealth is everything a ManagedClusterAddOn reports about its agent
+type AddonHealth struct {
+	Cluster          string
+	Name             string
+	InstallNamespace string
+	HealthCheckMode  addonv1alpha1.HealthCheckMode
+	Conditions       []metav1.Condition
+	RelatedObjects   []addonv1alpha1.ObjectReference
+	Registrations    []addonv1alpha1.RegistrationConfig
+	ConfigReferences []addonv1alpha1.ConfigReference
+}
+
+func NewAddonHealth(addon *addonv1alpha1.ManagedClusterAddOn) *AddonHealth {
+	return &AddonHealth{
+		Cluster:          addon.Namespace,
+		Name:             addon.Name,
+		InstallNamespace: addon.Status.Namespace,
+		HealthCheckMode:  addon.Status.HealthCheck.Mode,
+		Conditions:       addon.Status.Conditions,
+		RelatedObjects:   addon.Status.RelatedObjects,
+		Registrations:    addon.Status.Registrations,
+		ConfigReferences: addon.Status.ConfigReferences,
+	}
+}
+
+func GetManagedServiceAccountAddonHealth(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) (*AddonHealth, error) {
+	managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAddonHealth(managedServiceAccountAddon), nil
+}
+
+// Condition returns the condition of the type, nil when the addon does not report it
+func (h *AddonHealth) Condition(conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(h.Conditions, conditionType)
+}
+
+func (h *AddonHealth) IsConditionTrue(conditionType string) bool {
+	return meta.IsStatusConditionTrue(h.Conditions, conditionType)
+}
+
+// IsHealthy is true when the addon is Available and not Degraded
+func (h *AddonHealth) IsHealthy() bool {
+	return h.IsConditionTrue(addonv1alpha1.ManagedClusterAddOnConditionAvailable) &&
+		!h.IsConditionTrue(addonv1alpha1.ManagedClusterAddOnConditionDegraded)
+}
+
+// HasRelatedObject checks the addon lists the object, namespace is ignored when empty
+func (h *AddonHealth) HasRelatedObject(group, resource, namespace, name string) bool {
+	for _, obj := range h.RelatedObjects {
+		if obj.Group == group && obj.Resource == resource && obj.Name == name &&
+			(namespace == "" || obj.Namespace == namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration returns the registration for the signer, nil when the addon has none
+func (h *AddonHealth) Registration(signerName string) *addonv1alpha1.RegistrationConfig {
+	for i := range h.Registrations {
+		if h.Registrations[i].SignerName == signerName {
+			return &h.Registrations[i]
+		}
+	}
+	return nil
+}
+
+// Report renders the health as indented lines, meant for failure messages
+func (h *AddonHealth) Report() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "ManagedClusterAddOn %s/%s", h.Cluster, h.Name)
+	fmt.Fprintf(&b, "\n  install namespace: %s", valueOrUnset(h.InstallNamespace))
+	fmt.Fprintf(&b, "\n  health check mode: %s", valueOrUnset(string(h.HealthCheckMode)))
+
+	b.WriteString("\n  conditions:")
+	for _, conditionType := range AddonConditionTypes {
+		if condition := h.Condition(conditionType); condition != nil {
+			writeCondition(&b, condition)
+		} else {
+			fmt.Fprintf(&b, "\n    %s=<missing>", conditionType)
+		}
+	}
+	for i := range h.Conditions {
+		if !slices.Contains(AddonConditionTypes, h.Conditions[i].Type) {
+			writeCondition(&b, &h.Conditions[i])
+		}
+	}
+
+	b.WriteString("\n  related objects:")
+	if len(h.RelatedObjects) == 0 {
+		b.WriteString(" none")
+	}
+	for _, obj := range h.RelatedObjects {
+		fmt.Fprintf(&b, "\n    %s %s", groupResource(obj.Group, obj.Resource), namespacedName(obj.Namespace, obj.Name))
+	}
+
+	b.WriteString("\n  registrations:")
+	if len(h.Registrations) == 0 {
+		b.WriteString(" none")
+	}
+	for _, registration := range h.Registrations {
+		fmt.Fprintf(&b, "\n    %s user=%s groups=%s", registration.SignerName,
+			valueOrUnset(registration.Subject.User), strings.Join(registration.Subject.Groups, ","))
+	}
+
+	b.WriteString("\n  configs:")
+	if len(h.ConfigReferences) == 0 {
+		b.WriteString(" none")
+	}
+	for _, ref := range h.ConfigReferences {
+		desired, applied := "<unset>", "<unset>"
+		if ref.DesiredConfig != nil {
+			desired = ref.DesiredConfig.SpecHash
+		}
+		if ref.LastAppliedConfig != nil {
+			applied = ref.LastAppliedConfig.SpecHash
+		}
+		fmt.Fprintf(&b, "\n    %s %s desired=%s applied=%s",
+			groupResource(ref.Group, ref.Resource), namespacedName(ref.Namespace, ref.Name), desired, applied)
+	}
+
+	return b.String()
+}
+
+// addonHealthReport is the WaitError report of a ManagedClusterAddOn
+func addonHealthReport(u *unstructured.Unstructured) string {
+	addon, err := unstructuredToManagedClusterAddon(u)
+	if err != nil {
+		return ""
+	}
+	return NewAddonHealth(addon).Report()
+}
+
+func writeCondition(b *strings.Builder, condition *metav1.Condition) {
+	fmt.Fprintf(b, "\n    %s=%s reason=%s message=%q since=%s",
+		condition.Type, condition.Status, condition.Reason, condition.Message,
+		condition.LastTransitionTime.UTC().Format(time.RFC3339))
+}
+
+func valueOrUnset(value string) string {
+	if value == "" {
+		return "<unset>"
+	}
+	return value
+}
+
+func groupResource(group, resource string) string {
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
+
+func namespacedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	// ClusterProxyAddonName is the name of the cluster-proxy ClusterManagementAddOn and ManagedClusterAddOns
+	ClusterProxyAddonName = "cluster-proxy"
+	// ClusterProxyUserRouteName is the Route exposing the cluster-proxy user server on MCE hubs
+	ClusterProxyUserRouteName = "cluster-proxy-addon-user"
+)
+
+var gvrRoute = schema.GroupVersionResource{
+	Group:    "route.openshift.io",
+	Version:  "v1",
+	Resource: "routes",
+}
+
+// ClusterProxyNotInstalledError is returned when the managed cluster cannot be reached through cluster-proxy
+type ClusterProxyNotInstalledError struct {
+	Cluster string
+	Reason  string
+}
+
+func (e *ClusterProxyNotInstalledError) Error() string {
+	if e.Cluster == "" {
+		return fmt.Sprintf("cluster-proxy is not installed on the hub: %s", e.Reason)
+	}
+	return fmt.Sprintf("cluster-proxy is not available for managed cluster %s: %s", e.Cluster, e.Reason)
+}
+
+// CheckClusterProxyInstalled returns a *ClusterProxyNotInstalledError unless the cluster-proxy
+// ClusterManagementAddOn exists and its addon is Available on the managed cluster
+func CheckClusterProxyInstalled(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) error {
+	_, err := hubClient.Resource(gvrClusterManagementAddon).
+		Get(context.TODO(), ClusterProxyAddonName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return &ClusterProxyNotInstalledError{Reason: "ClusterManagementAddOn cluster-proxy not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	uAddon, err := hubClient.Resource(gvrManagedClusterAddon).Namespace(managedCluster.Name).
+		Get(context.TODO(), ClusterProxyAddonName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return &ClusterProxyNotInstalledError{Cluster: managedCluster.Name, Reason: "ManagedClusterAddOn cluster-proxy not found"}
+	}
+	if err != nil {
+		return err
+	}
+	addon, err := unstructuredToManagedClusterAddon(uAddon)
+	if err != nil {
+		return err
+	}
+	if health := NewAddonHealth(addon); !health.IsHealthy() {
+		return &ClusterProxyNotInstalledError{Cluster: managedCluster.Name, Reason: health.Report()}
+	}
+	return nil
+}
+
+// GetClusterProxyUserServerURL returns the https url of the cluster-proxy user server Route,
+// a *ClusterProxyNotInstalledError is returned when the hub has none
+func GetClusterProxyUserServerURL(hubClient dynamic.Interface) (string, error) {
+	uRouteList, err := hubClient.Resource(gvrRoute).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + ClusterProxyUserRouteName,
+	})
+	if errors.IsNotFound(err) {
+		return "", &ClusterProxyNotInstalledError{Reason: "the hub does not serve routes, set the cluster-proxy url in the options"}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, uRoute := range uRouteList.Items {
+		if uRoute.GetName() != ClusterProxyUserRouteName {
+			continue
+		}
+		host, _, err := unstructured.NestedString(uRoute.Object, "spec", "host")
+		if err != nil {
+			return "", err
+		}
+		if host != "" {
+			return "https://" + host, nil
+		}
+	}
+	return "", &ClusterProxyNotInstalledError{Reason: "Route " + ClusterProxyUserRouteName + " not found"}
+}
+
+// GetIngressCA returns the CA bundle of the default ingress certificate of an OpenShift hub,
+// which serves the cluster-proxy user server Route
+func GetIngressCA(hubClient dynamic.Interface) ([]byte, error) {
+	gvr := schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	uConfigMap, err := hubClient.Resource(gvr).Namespace("openshift-config-managed").
+		Get(context.TODO(), "default-ingress-cert", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ca, _, err := unstructured.NestedString(uConfigMap.Object, "data", "ca-bundle.crt")
+	if err != nil {
+		return nil, err
+	}
+	if ca == "" {
+		return nil, fmt.Errorf("empty ca-bundle.crt in configmap openshift-config-managed/default-ingress-cert")
+	}
+	return []byte(ca), nil
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// ClusterSelector filters the managed clusters the specs can run against, a cluster
+// also has to be Joined and Available to be selected
+type ClusterSelector struct {
+	// Names are the candidates in order of preference, nothing is selected without names
+	Names []string
+	// LabelSelector the ManagedCluster labels have to match, in the kubectl --selector syntax
+	LabelSelector string
+	// Claims are the ClusterClaims the cluster has to report with these values
+	Claims map[string]string
+	// MinKubernetesVersion is the lowest kubernetes version accepted, such as v1.27
+	MinKubernetesVersion string
+}
+
+// ClusterSelectionError lists why each candidate was rejected when not enough clusters match
+type ClusterSelectionError struct {
+	Wanted   int
+	Selected []string
+	// Rejected are the reasons by cluster name
+	Rejected map[string]string
+}
+
+func (e *ClusterSelectionError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d managed cluster(s) wanted, %d matched", e.Wanted, len(e.Selected))
+	if len(e.Selected) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(e.Selected, ", "))
+	}
+	if len(e.Rejected) == 0 {
+		b.WriteString(", no candidate found")
+		return b.String()
+	}
+
+	names := []string{}
+	for name := range e.Rejected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString(", rejected:")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %s", name, e.Rejected[name])
+	}
+	return b.String()
+}
+
+// SelectManagedClusters returns count clusters matching the selector, in the order of selector.Names.
+// a *ClusterSelectionError is returned when fewer clusters match, or when no names are given since the
+// hub may manage clusters the specs must not touch, such as local-cluster
+func SelectManagedClusters(
+	hubClient dynamic.Interface,
+	selector ClusterSelector,
+	count int,
+) ([]*clusterv1.ManagedCluster, error) {
+	labelSelector, err := labels.Parse(selector.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %v", selector.LabelSelector, err)
+	}
+	var minVersion *version.Version
+	if selector.MinKubernetesVersion != "" {
+		minVersion, err = version.ParseGeneric(selector.MinKubernetesVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minimum kubernetes version %q: %v", selector.MinKubernetesVersion, err)
+		}
+	}
+
+	candidates, rejected, err := listCandidateClusters(hubClient, selector.Names)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []*clusterv1.ManagedCluster{}
+	selectedNames := []string{}
+	for _, managedCluster := range candidates {
+		if len(selected) == count {
+			break
+		}
+		if reason := rejectManagedCluster(managedCluster, labelSelector, selector.Claims, minVersion); reason != "" {
+			rejected[managedCluster.Name] = reason
+			continue
+		}
+		selected = append(selected, managedCluster)
+		selectedNames = append(selectedNames, managedCluster.Name)
+	}
+
+	if len(selected) < count {
+		return nil, &ClusterSelectionError{
+			Wanted:   count,
+			Selected: selectedNames,
+			Rejected: rejected,
+		}
+	}
+	return selected, nil
+}
+
+// SelectManagedCluster returns the first cluster matching the selector
+func SelectManagedCluster(
+	hubClient dynamic.Interface,
+	selector ClusterSelector,
+) (*clusterv1.ManagedCluster, error) {
+	selected, err := SelectManagedClusters(hubClient, selector, 1)
+	if err != nil {
+		return nil, err
+	}
+	return selected[0], nil
+}
+
+// listCandidateClusters gets the named clusters, names missing from the hub are rejected
+func listCandidateClusters(
+	hubClient dynamic.Interface,
+	names []string,
+) ([]*clusterv1.ManagedCluster, map[string]string, error) {
+	candidates := []*clusterv1.ManagedCluster{}
+	rejected := map[string]string{}
+
+	for _, name := range names {
+		managedCluster, err := GetManagedCluster(hubClient, name)
+		if errors.IsNotFound(err) {
+			rejected[name] = "not registered on the hub"
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, managedCluster)
+	}
+	return candidates, rejected, nil
+}
+
+// rejectManagedCluster returns why the cluster does not match, empty when it does
+func rejectManagedCluster(
+	managedCluster *clusterv1.ManagedCluster,
+	labelSelector labels.Selector,
+	claims map[string]string,
+	minVersion *version.Version,
+) string {
+	if managedCluster.DeletionTimestamp != nil {
+		return "being deleted"
+	}
+	for _, conditionType := range []string{clusterv1.ManagedClusterConditionJoined, clusterv1.ManagedClusterConditionAvailable} {
+		condition := meta.FindStatusCondition(managedCluster.Status.Conditions, conditionType)
+		if condition == nil {
+			return fmt.Sprintf("condition %s not reported", conditionType)
+		}
+		if condition.Status != metav1.ConditionTrue {
+			return fmt.Sprintf("condition %s=%s reason=%s message=%q",
+				conditionType, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+
+	if !labelSelector.Matches(labels.Set(managedCluster.Labels)) {
+		return fmt.Sprintf("labels %v do not match %q", managedCluster.Labels, labelSelector.String())
+	}
+
+	reported := map[string]string{}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		reported[claim.Name] = claim.Value
+	}
+	claimNames := []string{}
+	for name := range claims {
+		claimNames = append(claimNames, name)
+	}
+	sort.Strings(claimNames)
+	for _, name := range claimNames {
+		value, ok := reported[name]
+		if !ok {
+			return fmt.Sprintf("ClusterClaim %s not reported", name)
+		}
+		if value != claims[name] {
+			return fmt.Sprintf("ClusterClaim %s=%s instead of %s", name, value, claims[name])
+		}
+	}
+
+	if minVersion != nil {
+		kubernetesVersion := managedCluster.Status.Version.Kubernetes
+		if kubernetesVersion == "" {
+			return "kubernetes version not reported"
+		}
+		v, err := version.ParseGeneric(kubernetesVersion)
+		if err != nil {
+			return fmt.Sprintf("kubernetes version %q cannot be parsed: %v", kubernetesVersion, err)
+		}
+		if v.LessThan(minVersion) {
+			return fmt.Sprintf("kubernetes version %s older than v%s", kubernetesVersion, minVersion)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// InstallModeMultiClusterEngine turns the feature on and off through the MCE component
+	InstallModeMultiClusterEngine = "mce"
+	// InstallModeUpstream installs the open-cluster-management.io release on a hub without MCE
+	InstallModeUpstream = "upstream"
+)
+
+// FeatureInstaller enables and disables the managed-serviceaccount feature on a hub
+type FeatureInstaller interface {
+	// Name is the install mode of the installer
+	Name() string
+	Enable(hubClient dynamic.Interface) error
+	Disable(hubClient dynamic.Interface) error
+	IsEnabled(hubClient dynamic.Interface) (bool, error)
+}
+
+// featureInstaller is used by EnableManagedServiceAccountFeature and friends
+var featureInstaller FeatureInstaller = &MultiClusterEngineInstaller{}
+
+func SetFeatureInstaller(installer FeatureInstaller) {
+	featureInstaller = installer
+}
+
+func GetFeatureInstaller() FeatureInstaller {
+	return featureInstaller
+}
+
+// NewFeatureInstaller returns the installer of the mode, an empty mode picks the MCE installer
+// when the hub has an MCE and the upstream installer otherwise
+func NewFeatureInstaller(hubClient dynamic.Interface, mode string, upstreamImage string) (FeatureInstaller, error) {
+	if mode == "" {
+		hasMCE, err := hasMultiClusterEngine(hubClient)
+		if err != nil {
+			return nil, err
+		}
+		mode = InstallModeUpstream
+		if hasMCE {
+			mode = InstallModeMultiClusterEngine
+		}
+	}
+
+	switch mode {
+	case InstallModeMultiClusterEngine:
+		return &MultiClusterEngineInstaller{}, nil
+	case InstallModeUpstream:
+		return &UpstreamInstaller{Image: upstreamImage}, nil
+	default:
+		return nil, fmt.Errorf("unknown install mode %q, expecting %q or %q",
+			mode, InstallModeMultiClusterEngine, InstallModeUpstream)
+	}
+}
+
+// hasMultiClusterEngine is false when the MCE api is not served or there is no MCE
+func hasMultiClusterEngine(hubClient dynamic.Interface) (bool, error) {
+	uMCEList, err := hubClient.Resource(gvrMCE).List(context.TODO(), metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(uMCEList.Items) > 0, nil
+}
+
+// MultiClusterEngineInstaller toggles the managedserviceaccount component of the MCE
+type MultiClusterEngineInstaller struct{}
+
+func (i *MultiClusterEngineInstaller) Name() string {
+	return InstallModeMultiClusterEngine
+}
+
+func (i *MultiClusterEngineInstaller) Enable(hubClient dynamic.Interface) error {
+	// on ACM hubs the MCH manages the MCE, and its admission webhook
+	// multiclusterhub.validating-webhook.open-cluster-management.io may reject the component,
+	// SetManagedServiceAccountFeature picks the path that works
+	return SetManagedServiceAccountFeature(hubClient, true)
+}
+
+func (i *MultiClusterEngineInstaller) Disable(hubClient dynamic.Interface) error {
+	return SetManagedServiceAccountFeature(hubClient, false)
+}
+
+func (i *MultiClusterEngineInstaller) IsEnabled(hubClient dynamic.Interface) (bool, error) {
+	return isMultiClusterEngineFeatureEnabled(hubClient)
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+)
+
+// ManagedServiceAccountSnapshot is the state of the hub the suite may change,
+// taken before the specs run so it can be put back afterwards
+type ManagedServiceAccountSnapshot struct {
+	// InstallMode is the name of the FeatureInstaller the snapshot was taken with
+	InstallMode string
+	// Enabled is whether the feature was enabled, the upstream installer restores only that
+	Enabled bool
+	// Component is the managedserviceaccount entry of spec.overrides.components, nil when there was none
+	Component map[string]interface{}
+	// MultiClusterHub is the namespace/name of the MCH managing the MCE, empty on a standalone MCE hub
+	MultiClusterHub string
+	// MultiClusterHubComponent is the managedserviceaccount entry of the MCH, nil when there was none
+	MultiClusterHubComponent map[string]interface{}
+	// Addons are the managed-serviceaccount ManagedClusterAddOns by cluster name
+	Addons map[string]*addonv1alpha1.ManagedClusterAddOn
+}
+
+func listManagedServiceAccountAddons(hubClient dynamic.Interface) (map[string]*addonv1alpha1.ManagedClusterAddOn, error) {
+	uAddonList, err := hubClient.Resource(gvrManagedClusterAddon).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=managed-serviceaccount",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addons := map[string]*addonv1alpha1.ManagedClusterAddOn{}
+	for i := range uAddonList.Items {
+		if uAddonList.Items[i].GetName() != "managed-serviceaccount" {
+			continue
+		}
+		addon, err := unstructuredToManagedClusterAddon(&uAddonList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		addons[addon.Namespace] = addon
+	}
+	return addons, nil
+}
+
+// ListManagedServiceAccountAddonClusters returns the sorted names of the clusters with a managed-serviceaccount ManagedClusterAddOn
+func ListManagedServiceAccountAddonClusters(hubClient dynamic.Interface) ([]string, error) {
+	addons, err := listManagedServiceAccountAddons(hubClient)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []string{}
+	for cluster := range addons {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+func SnapshotManagedServiceAccountState(hubClient dynamic.Interface) (*ManagedServiceAccountSnapshot, error) {
+	enabled, err := featureInstaller.IsEnabled(hubClient)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &ManagedServiceAccountSnapshot{
+		InstallMode: featureInstaller.Name(),
+		Enabled:     enabled,
+	}
+
+	// the api is not served while the feature has never been enabled
+	snapshot.Addons, err = listManagedServiceAccountAddons(hubClient)
+	if errors.IsNotFound(err) {
+		snapshot.Addons = map[string]*addonv1alpha1.ManagedClusterAddOn{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if snapshot.InstallMode != InstallModeMultiClusterEngine {
+		return snapshot, nil
+	}
+
+	mce, err := GetMultiClusterEngine(hubClient)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Component, err = getComponent(mce, managedServiceAccountComponent)
+	if err != nil {
+		return nil, err
+	}
+
+	mch, err := GetManagingMultiClusterHub(hubClient, mce)
+	if err != nil {
+		return nil, err
+	}
+	if mch != nil {
+		snapshot.MultiClusterHub = mch.GetNamespace() + "/" + mch.GetName()
+		snapshot.MultiClusterHubComponent, err = getComponent(mch, managedServiceAccountComponent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// RestoreManagedServiceAccountState puts back the addons and the MCE and MCH component entries of the snapshot,
+// or with another installer whether the feature was enabled. addons created since are deleted, and addons
+// deleted since are created again from their spec, addons that exist in both are left alone.
+// addons of the install strategy are left to it
+func RestoreManagedServiceAccountState(hubClient dynamic.Interface, snapshot *ManagedServiceAccountSnapshot) error {
+	addons, err := listManagedServiceAccountAddons(hubClient)
+	if errors.IsNotFound(err) {
+		addons = map[string]*addonv1alpha1.ManagedClusterAddOn{}
+	} else if err != nil {
+		return err
+	}
+
+	for cluster, addon := range addons {
+		if _, ok := snapshot.Addons[cluster]; ok || isOwnedByClusterManagementAddon(addon) {
+			continue
+		}
+		err := hubClient.Resource(gvrManagedClusterAddon).Namespace(cluster).
+			Delete(context.TODO(), "managed-serviceaccount", metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	for cluster, addon := range snapshot.Addons {
+		if _, ok := addons[cluster]; ok || isOwnedByClusterManagementAddon(addon) {
+			continue
+		}
+		_, err := RecreateManagedServiceAccountAddon(hubClient, addon)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	if snapshot.InstallMode != InstallModeMultiClusterEngine {
+		if snapshot.Enabled {
+			return nil
+		}
+		return featureInstaller.Disable(hubClient)
+	}
+
+	// the MCH goes first so it does not reconcile the MCE away from the snapshot
+	if snapshot.MultiClusterHub != "" {
+		namespace, name, _ := strings.Cut(snapshot.MultiClusterHub, "/")
+		mchResource := hubClient.Resource(gvrMCH).Namespace(namespace)
+		err := patchComponent(
+			mchResource,
+			func() (*unstructured.Unstructured, error) {
+				return mchResource.Get(context.TODO(), name, metav1.GetOptions{})
+			},
+			managedServiceAccountComponent,
+			func(map[string]interface{}) map[string]interface{} {
+				return snapshot.MultiClusterHubComponent
+			},
+			false,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return patchMultiClusterEngineComponent(hubClient, managedServiceAccountComponent,
+		func(map[string]interface{}) map[string]interface{} {
+			return snapshot.Component
+		},
+	)
+}
@@ -21,18 +21,16 @@ func toUnstructured(
 	return &unstructured.Unstructured{Object: u}, nil
 }
 
+// GetImportedCluster returns the first cluster of the options that is registered, Joined and Available
 func GetImportedCluster(hubClient dynamic.Interface) (*clusterv1.ManagedCluster, error) {
-	for _, optionsManagedCluster := range libgooptions.TestOptions.Options.ManagedClusters {
-		clusterName := optionsManagedCluster.Name
-		// make sure that the cluster is already imported
-		managedCluster, err := GetManagedCluster(hubClient, clusterName)
-		if err != nil {
-			continue
-		}
+	return SelectManagedCluster(hubClient, ClusterSelector{Names: OptionsClusterNames()})
+}
 
-		return managedCluster, nil
+// OptionsClusterNames are the names of the managed clusters of the options, in their order
+func OptionsClusterNames() []string {
+	names := []string{}
+	for _, optionsManagedCluster := range libgooptions.TestOptions.Options.ManagedClusters {
+		names = append(names, optionsManagedCluster.Name)
 	}
-
-	//TODO: throw error?
-	return nil, nil
+	return names
 }
@@ -2,15 +2,23 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
+var gvrManagedCluster = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "managedclusters",
+}
+
 func unstructuredToManagedCluster(
 	u *unstructured.Unstructured,
 ) (*clusterv1.ManagedCluster, error) {
@@ -29,13 +37,7 @@ func GetManagedCluster(
 	hubClient dynamic.Interface,
 	clusterName string,
 ) (*clusterv1.ManagedCluster, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "cluster.open-cluster-management.io",
-		Version:  "v1",
-		Resource: "managedclusters",
-	}
-
-	uManagedCluster, err := hubClient.Resource(gvr).Get(context.TODO(), clusterName, metav1.GetOptions{})
+	uManagedCluster, err := hubClient.Resource(gvrManagedCluster).Get(context.TODO(), clusterName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +53,48 @@ func GetManagedCluster(
 
 	return managedCluster, nil
 }
+
+// SetManagedClusterLabel adds or updates one label of the ManagedCluster
+func SetManagedClusterLabel(
+	hubClient dynamic.Interface,
+	clusterName string,
+	key string,
+	value string,
+) error {
+	return patchManagedClusterLabel(hubClient, clusterName, key, &value)
+}
+
+// RemoveManagedClusterLabel removes one label of the ManagedCluster, a missing label is not an error
+func RemoveManagedClusterLabel(
+	hubClient dynamic.Interface,
+	clusterName string,
+	key string,
+) error {
+	return patchManagedClusterLabel(hubClient, clusterName, key, nil)
+}
+
+// patchManagedClusterLabel merge patches the label so the other labels are left alone, nil removes it
+func patchManagedClusterLabel(
+	hubClient dynamic.Interface,
+	clusterName string,
+	key string,
+	value *string,
+) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]*string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = hubClient.Resource(gvrManagedCluster).Patch(
+		context.TODO(),
+		clusterName,
+		types.MergePatchType,
+		patch,
+		metav1.PatchOptions{},
+	)
+	return err
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// BootstrapManagedServiceAccountName is the ManagedServiceAccount the suite reaches a managed cluster
+// with when it only has hub credentials, its RBAC is delivered by the ManifestWork of the same name
+const BootstrapManagedServiceAccountName = "managed-serviceaccount-e2e-bootstrap"
+
+// NewClusterAdminManifestWork builds a ManifestWork that binds cluster-admin to the ServiceAccount
+func NewClusterAdminManifestWork(
+	managedCluster *clusterv1.ManagedCluster,
+	workName string,
+	serviceAccountNamespace string,
+	serviceAccountName string,
+) *workv1.ManifestWork {
+	return NewClusterRoleBindingManifestWork(managedCluster, workName, serviceAccountNamespace, serviceAccountName, "cluster-admin")
+}
+
+// NewClusterRoleBindingManifestWork builds a ManifestWork that binds the existing ClusterRole to the
+// ServiceAccount, the ClusterRoleBinding is named after the work
+func NewClusterRoleBindingManifestWork(
+	managedCluster *clusterv1.ManagedCluster,
+	workName string,
+	serviceAccountNamespace string,
+	serviceAccountName string,
+	clusterRole string,
+) *workv1.ManifestWork {
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: workName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     clusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      serviceAccountName,
+				Namespace: serviceAccountNamespace,
+			},
+		},
+	}
+
+	return NewManifestWork(managedCluster, workName, clusterRoleBinding)
+}
+
+// BootstrapManagedClusterAccess makes the bootstrap ManagedServiceAccount cluster-admin on the managed cluster.
+// what already exists is reused, so it is cheap to call again once access is set up.
+// the managed-serviceaccount addon has to be available on the cluster
+func BootstrapManagedClusterAccess(
+	ctx context.Context,
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) error {
+	_, err := GetManagedServiceAccount(hubClient, managedCluster, BootstrapManagedServiceAccountName)
+	if errors.IsNotFound(err) {
+		_, err = CreateManagedServiceAccountWithOptions(
+			hubClient,
+			managedCluster,
+			WithName(BootstrapManagedServiceAccountName),
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = WaitForManagedServiceAccountReady(ctx, hubClient, managedCluster, BootstrapManagedServiceAccountName)
+	if err != nil {
+		return err
+	}
+
+	serviceAccountNamespace, err := GetManagedServiceAccountNamespace(hubClient, managedCluster)
+	if err != nil {
+		return err
+	}
+	work := NewClusterAdminManifestWork(
+		managedCluster,
+		BootstrapManagedServiceAccountName,
+		serviceAccountNamespace,
+		BootstrapManagedServiceAccountName,
+	)
+	existing, err := GetManifestWork(hubClient, managedCluster, work.Name)
+	switch {
+	case errors.IsNotFound(err):
+		_, err = CreateManifestWork(hubClient, work)
+	case err == nil && !sameManifests(existing.Spec.Workload.Manifests, work.Spec.Workload.Manifests):
+		// the install namespace changed since the work was created
+		existing.Spec.Workload = work.Spec.Workload
+		_, err = UpdateManifestWork(hubClient, existing)
+	}
+	if err != nil {
+		return err
+	}
+
+	for !IsManifestWorkAvailable(hubClient, managedCluster, work.Name) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ManifestWork %s/%s granting cluster-admin to %s/%s is not available: %v",
+				managedCluster.Name, work.Name, serviceAccountNamespace, BootstrapManagedServiceAccountName, ctx.Err())
+		case <-time.After(WaitPollInterval):
+		}
+	}
+	return nil
+}
+
+// sameManifests compares the manifests as JSON documents, whether they are raw or typed objects
+func sameManifests(a, b []workv1.Manifest) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		docA, errA := manifestDocument(a[i])
+		docB, errB := manifestDocument(b[i])
+		if errA != nil || errB != nil || !reflect.DeepEqual(docA, docB) {
+			return false
+		}
+	}
+	return true
+}
+
+func manifestDocument(manifest workv1.Manifest) (interface{}, error) {
+	raw := manifest.Raw
+	if manifest.Object != nil {
+		var err error
+		if raw, err = json.Marshal(manifest.Object); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc interface{}
+	err := json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// RemoveManagedClusterAccess deletes the ManifestWork and the ManagedServiceAccount of BootstrapManagedClusterAccess
+func RemoveManagedClusterAccess(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) error {
+	err := DeleteManifestWork(hubClient, managedCluster, BootstrapManagedServiceAccountName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = DeleteManagedServiceAccount(hubClient, managedCluster, BootstrapManagedServiceAccountName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// RemoveAllManagedClusterAccess runs RemoveManagedClusterAccess for every cluster with a bootstrap
+// ManifestWork or ManagedServiceAccount
+func RemoveAllManagedClusterAccess(hubClient dynamic.Interface) error {
+	listOptions := metav1.ListOptions{FieldSelector: "metadata.name=" + BootstrapManagedServiceAccountName}
+
+	clusters := map[string]bool{}
+	for _, gvr := range []schema.GroupVersionResource{gvrManifestWork, managedServiceAccountGVR(managedServiceAccountVersion)} {
+		uList, err := hubClient.Resource(gvr).List(context.TODO(), listOptions)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, u := range uList.Items {
+			clusters[u.GetNamespace()] = true
+		}
+	}
+
+	for cluster := range clusters {
+		managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: cluster}}
+		if err := RemoveManagedClusterAccess(hubClient, managedCluster); err != nil {
+			return err
+		}
+	}
+	return nil
+}
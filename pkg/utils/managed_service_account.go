@@ -3,7 +3,6 @@ package utils
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog"
@@ -19,30 +18,22 @@ import (
 	msav1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
 )
 
-func unstructuredToManagedServiceAccount(
-	u *unstructured.Unstructured,
+func GetManagedServiceAccount(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
 ) (*msav1beta1.ManagedServiceAccount, error) {
-	msa := &msav1beta1.ManagedServiceAccount{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
-		u.UnstructuredContent(),
-		msa,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return msa, nil
+	return GetManagedServiceAccountWithVersion(hubClient, managedServiceAccountVersion, managedCluster, name)
 }
 
-func GetManagedServiceAccount(
+// GetManagedServiceAccountWithVersion reads the ManagedServiceAccount through the given api version
+func GetManagedServiceAccountWithVersion(
 	hubClient dynamic.Interface,
+	version string,
 	managedCluster *clusterv1.ManagedCluster,
 	name string,
 ) (*msav1beta1.ManagedServiceAccount, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "authentication.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedserviceaccounts",
-	}
+	gvr := managedServiceAccountGVR(version)
 
 	uManagedServiceAccount, err := hubClient.Resource(gvr).
 		Namespace(managedCluster.Name).
@@ -59,15 +50,49 @@ func GetManagedServiceAccount(
 	return managedServiceAccount, nil
 }
 
+// GetUnstructuredManagedServiceAccount reads the ManagedServiceAccount through the given api version
+// as the hub serves it, without converting it on the client
+func GetUnstructuredManagedServiceAccount(
+	hubClient dynamic.Interface,
+	version string,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) (*unstructured.Unstructured, error) {
+	return hubClient.Resource(managedServiceAccountGVR(version)).
+		Namespace(managedCluster.Name).
+		Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// UpdateUnstructuredManagedServiceAccount writes the ManagedServiceAccount through the api version of the object
+func UpdateUnstructuredManagedServiceAccount(
+	hubClient dynamic.Interface,
+	uManagedServiceAccount *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	gvr := managedServiceAccountGVR(uManagedServiceAccount.GroupVersionKind().Version)
+
+	return hubClient.Resource(gvr).
+		Namespace(uManagedServiceAccount.GetNamespace()).
+		Update(context.TODO(), uManagedServiceAccount, metav1.UpdateOptions{})
+}
+
+// UpdateUnstructuredManagedServiceAccountStatus writes the status of the ManagedServiceAccount
+// through the api version of the object
+func UpdateUnstructuredManagedServiceAccountStatus(
+	hubClient dynamic.Interface,
+	uManagedServiceAccount *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	gvr := managedServiceAccountGVR(uManagedServiceAccount.GroupVersionKind().Version)
+
+	return hubClient.Resource(gvr).
+		Namespace(uManagedServiceAccount.GetNamespace()).
+		UpdateStatus(context.TODO(), uManagedServiceAccount, metav1.UpdateOptions{})
+}
+
 func ListManagedServiceAccount(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
 ) (*msav1beta1.ManagedServiceAccountList, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "authentication.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedserviceaccounts",
-	}
+	gvr := managedServiceAccountGVR(managedServiceAccountVersion)
 
 	uList, err := hubClient.Resource(gvr).
 		Namespace(managedCluster.Name).
@@ -104,32 +129,33 @@ func CreateManagedServiceAccount(
 	managedCluster *clusterv1.ManagedCluster,
 	namePrefix string,
 ) (*msav1beta1.ManagedServiceAccount, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "authentication.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedserviceaccounts",
-	}
+	return CreateManagedServiceAccountWithOptions(hubClient, managedCluster, WithGenerateName(namePrefix))
+}
 
-	newManagedServiceAccount := &msav1beta1.ManagedServiceAccount{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ManagedServiceAccount",
-			APIVersion: "authentication.open-cluster-management.io/v1alpha1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: namePrefix,
-			Namespace:    managedCluster.Name,
-		},
-		Spec: msav1beta1.ManagedServiceAccountSpec{
-			Rotation: msav1beta1.ManagedServiceAccountRotation{
-				Enabled: true,
-				Validity: metav1.Duration{
-					Duration: time.Hour,
-				},
-			},
-		},
-	}
+// CreateManagedServiceAccountWithOptions creates a ManagedServiceAccount built by NewManagedServiceAccount
+func CreateManagedServiceAccountWithOptions(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	opts ...ManagedServiceAccountOption,
+) (*msav1beta1.ManagedServiceAccount, error) {
+	return CreateManagedServiceAccountWithVersion(hubClient, managedServiceAccountVersion, managedCluster, opts...)
+}
 
-	uNewManagedServiceAccount, err := toUnstructured(newManagedServiceAccount)
+// CreateManagedServiceAccountWithVersion creates the ManagedServiceAccount through the given api version
+func CreateManagedServiceAccountWithVersion(
+	hubClient dynamic.Interface,
+	version string,
+	managedCluster *clusterv1.ManagedCluster,
+	opts ...ManagedServiceAccountOption,
+) (*msav1beta1.ManagedServiceAccount, error) {
+	gvr := managedServiceAccountGVR(version)
+
+	newManagedServiceAccount := NewManagedServiceAccount(managedCluster, opts...)
+
+	uNewManagedServiceAccount, err := managedServiceAccountToUnstructured(
+		newManagedServiceAccount,
+		version,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +181,35 @@ func CreateManagedServiceAccount(
 	return managedServiceAccount, nil
 }
 
+// UpdateManagedServiceAccountWithVersion writes the spec and metadata of the ManagedServiceAccount
+// through the given api version, the object must carry the resourceVersion it was read with
+func UpdateManagedServiceAccountWithVersion(
+	hubClient dynamic.Interface,
+	version string,
+	managedServiceAccount *msav1beta1.ManagedServiceAccount,
+) (*msav1beta1.ManagedServiceAccount, error) {
+	gvr := managedServiceAccountGVR(version)
+
+	uManagedServiceAccount, err := managedServiceAccountToUnstructured(managedServiceAccount, version)
+	if err != nil {
+		return nil, err
+	}
+
+	uUpdatedManagedServiceAccount, err := hubClient.
+		Resource(gvr).
+		Namespace(managedServiceAccount.Namespace).
+		Update(
+			context.TODO(),
+			uManagedServiceAccount,
+			metav1.UpdateOptions{},
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManagedServiceAccount(uUpdatedManagedServiceAccount)
+}
+
 func DoesManagedServiceAccountExist(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
@@ -258,6 +313,138 @@ func GetManagedServiceAccountSecret(
 	return secret, nil
 }
 
+// DoesManagedServiceAccountSecretExist checks the token secret on the hub, the name comes from Status.TokenSecretRef
+func DoesManagedServiceAccountSecretExist(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	secretName string,
+) bool {
+	_, err := getSecret(hubClient, secretName, managedCluster.Name)
+	if errors.IsNotFound(err) {
+		return false
+	}
+	// NOTE: only false is trustworthy true is not
+	return true
+}
+
+func DeleteManagedServiceAccountSecret(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	secretName string,
+) error {
+	gvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "secrets",
+	}
+
+	return hubClient.Resource(gvr).Namespace(managedCluster.Name).Delete(
+		context.TODO(),
+		secretName,
+		metav1.DeleteOptions{},
+	)
+}
+
+// SetManagedServiceAccountSecretToken overwrites the token stored in the hub secret
+func SetManagedServiceAccountSecretToken(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	secretName string,
+	token []byte,
+) error {
+	gvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "secrets",
+	}
+
+	secret, err := getSecret(hubClient, secretName, managedCluster.Name)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["token"] = token
+
+	uSecret, err := toUnstructured(secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = hubClient.Resource(gvr).Namespace(managedCluster.Name).Update(
+		context.TODO(),
+		uSecret,
+		metav1.UpdateOptions{},
+	)
+	return err
+}
+
+func unstructuredToServiceAccount(
+	u *unstructured.Unstructured,
+) (*corev1.ServiceAccount, error) {
+	sa := &corev1.ServiceAccount{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), sa)
+	if err != nil {
+		return nil, err
+	}
+	return sa, nil
+}
+
+// GetManagedServiceAccountServiceAccount reads the ServiceAccount backing the ManagedServiceAccount on the managed cluster
+func GetManagedServiceAccountServiceAccount(
+	mcDynClient dynamic.Interface,
+	namespace string,
+	name string,
+) (*corev1.ServiceAccount, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "serviceaccounts",
+	}
+
+	uServiceAccount, err := mcDynClient.
+		Resource(gvr).
+		Namespace(namespace).
+		Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToServiceAccount(uServiceAccount)
+}
+
+func DoesManagedServiceAccountServiceAccountExist(
+	mcDynClient dynamic.Interface,
+	namespace string,
+	name string,
+) bool {
+	_, err := GetManagedServiceAccountServiceAccount(mcDynClient, namespace, name)
+	if errors.IsNotFound(err) {
+		return false
+	}
+	// NOTE: only false is trustworthy true is not
+	return true
+}
+
+func DeleteManagedServiceAccountServiceAccount(
+	mcDynClient dynamic.Interface,
+	namespace string,
+	name string,
+) error {
+	gvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "serviceaccounts",
+	}
+
+	return mcDynClient.Resource(gvr).Namespace(namespace).Delete(
+		context.TODO(),
+		name,
+		metav1.DeleteOptions{},
+	)
+}
+
 func GetManagedServiceAccountToken(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
@@ -280,11 +467,17 @@ func DeleteManagedServiceAccount(
 	managedCluster *clusterv1.ManagedCluster,
 	name string,
 ) error {
-	gvr := schema.GroupVersionResource{
-		Group:    "authentication.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedserviceaccounts",
-	}
+	return DeleteManagedServiceAccountWithVersion(hubClient, managedServiceAccountVersion, managedCluster, name)
+}
+
+// DeleteManagedServiceAccountWithVersion deletes the ManagedServiceAccount through the given api version
+func DeleteManagedServiceAccountWithVersion(
+	hubClient dynamic.Interface,
+	version string,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) error {
+	gvr := managedServiceAccountGVR(version)
 
 	err := hubClient.Resource(gvr).Namespace(managedCluster.Name).Delete(
 		context.TODO(),
@@ -295,19 +488,37 @@ func DeleteManagedServiceAccount(
 	return err
 }
 
-func GetManagedServiceAccountUserName(
+// GetManagedServiceAccountNamespace returns the namespace on the managed cluster
+// where the addon agent creates the ServiceAccounts
+func GetManagedServiceAccountNamespace(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
-	managedServiceAccountName string,
 ) (string, error) {
 	managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
 	if err != nil {
 		return "", err
 	}
 
+	// status is only filled in once the addon is registered
 	ns := managedServiceAccountAddon.Status.Namespace
 	if ns == "" {
-		ns = "open-cluster-management-agent-addon"
+		ns = managedServiceAccountAddon.Spec.InstallNamespace
+	}
+	if ns == "" {
+		ns = AddonFrameworkInstallNamespace
+	}
+
+	return ns, nil
+}
+
+func GetManagedServiceAccountUserName(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+) (string, error) {
+	ns, err := GetManagedServiceAccountNamespace(hubClient, managedCluster)
+	if err != nil {
+		return "", err
 	}
 	name := fmt.Sprintf("system:serviceaccount:%s:%s", ns, managedServiceAccountName)
 
@@ -366,6 +577,46 @@ func ValidateManagedServiceAccountToken(
 	return true, nil
 }
 
+// IsManagedServiceAccountTokenAuthenticated runs a TokenReview and reports whether the token is
+// authenticated, unlike ValidateManagedServiceAccountToken a rejected token is not an error
+func IsManagedServiceAccountTokenAuthenticated(
+	mcDynClient dynamic.Interface,
+	token string,
+) (bool, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "authentication.k8s.io",
+		Version:  "v1",
+		Resource: "tokenreviews",
+	}
+
+	newTokenReview := &authv1.TokenReview{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "TokenReview",
+			APIVersion: "authentication.k8s.io/v1",
+		},
+		Spec: authv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+
+	uNewTokenReview, err := toUnstructured(newTokenReview)
+	if err != nil {
+		return false, err
+	}
+
+	uCreatedTokenReview, err := mcDynClient.Resource(gvr).Create(context.TODO(), uNewTokenReview, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	createdTokenReview, err := unstructuredToTokenReview(uCreatedTokenReview)
+	if err != nil {
+		return false, err
+	}
+
+	return createdTokenReview.Status.Authenticated, nil
+}
+
 func unstructuredToTokenReview(u *unstructured.Unstructured) (*authv1.TokenReview, error) {
 	tr := &authv1.TokenReview{}
 	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
@@ -377,3 +628,20 @@ func unstructuredToTokenReview(u *unstructured.Unstructured) (*authv1.TokenRevie
 	}
 	return tr, nil
 }
+
+// DoesManagedServiceAccountCRDExist checks the ManagedServiceAccount CRD is installed on the hub,
+// only false is trustworthy
+func DoesManagedServiceAccountCRDExist(hubClient dynamic.Interface) bool {
+	gvr := schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
+
+	_, err := hubClient.Resource(gvr).Get(
+		context.TODO(),
+		"managedserviceaccounts."+ManagedServiceAccountGroup,
+		metav1.GetOptions{},
+	)
+	return !errors.IsNotFound(err)
+}
@@ -14,6 +14,15 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
+const (
+	// DefaultManagedServiceAccountInstallNamespace is where the suite installs the agent
+	// when the options do not set an install namespace
+	DefaultManagedServiceAccountInstallNamespace = "open-cluster-management-managed-serviceaccount"
+	// AddonFrameworkInstallNamespace is where the addon framework installs the agent
+	// when the ManagedClusterAddOn does not ask for a namespace
+	AddonFrameworkInstallNamespace = "open-cluster-management-agent-addon"
+)
+
 var gvrMCH = schema.GroupVersionResource{
 	Group:    "operator.open-cluster-management.io",
 	Version:  "v1",
@@ -24,6 +33,16 @@ var gvrMCE = schema.GroupVersionResource{
 	Version:  "v1",
 	Resource: "multiclusterengines",
 }
+var gvrManagedClusterAddon = schema.GroupVersionResource{
+	Group:    "addon.open-cluster-management.io",
+	Version:  "v1alpha1",
+	Resource: "managedclusteraddons",
+}
+var gvrClusterManagementAddon = schema.GroupVersionResource{
+	Group:    "addon.open-cluster-management.io",
+	Version:  "v1alpha1",
+	Resource: "clustermanagementaddons",
+}
 
 func unstructuredToManagedClusterAddon(
 	u *unstructured.Unstructured,
@@ -40,6 +59,33 @@ func unstructuredToManagedClusterAddon(
 	return mca, nil
 }
 
+func unstructuredToClusterManagementAddon(
+	u *unstructured.Unstructured,
+) (*addonv1alpha1.ClusterManagementAddOn, error) {
+	cma := &addonv1alpha1.ClusterManagementAddOn{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		cma,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cma, nil
+}
+
+func GetManagedServiceAccountClusterManagementAddon(
+	hubClient dynamic.Interface,
+) (*addonv1alpha1.ClusterManagementAddOn, error) {
+	uClusterManagementAddon, err := hubClient.Resource(gvrClusterManagementAddon).
+		Get(context.TODO(), "managed-serviceaccount", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToClusterManagementAddon(uClusterManagementAddon)
+}
+
 func DoesManagedServiceAccountAddonExist(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
@@ -57,19 +103,12 @@ func IsManagedServiceAccountAddonAvailable(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
 ) bool {
-	managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
+	health, err := GetManagedServiceAccountAddonHealth(hubClient, managedCluster)
 	if err != nil {
 		return false
 	}
 
-	for _, condition := range managedServiceAccountAddon.Status.Conditions {
-		if condition.Type == addonv1alpha1.ManagedClusterAddOnConditionAvailable {
-			if condition.Status == "True" {
-				return true
-			}
-		}
-	}
-	return false
+	return health.IsConditionTrue(addonv1alpha1.ManagedClusterAddOnConditionAvailable)
 }
 
 // list globally and get first mch, will return no error and no obj if not found
@@ -105,68 +144,22 @@ func GetMultiClusterEngine(
 }
 
 func EnableManagedServiceAccountFeature(hubClient dynamic.Interface) error {
-	// can only modify mce to enable managedserviceaccount
-	// modify mch will be rejected by admission webhook multiclusterhub.validating-webhook.open-cluster-management.io.
-	mce, err := GetMultiClusterEngine(hubClient)
-	if err != nil {
-		return err
-	}
-	err = SetManagedServiceAcccount(mce, true)
-	if err != nil {
-		return err
-	}
-	_, err = hubClient.Resource(gvrMCE).Namespace("").Update(context.TODO(), mce, metav1.UpdateOptions{})
-	return err
+	return featureInstaller.Enable(hubClient)
 }
 
-func SetManagedServiceAcccount(m *unstructured.Unstructured, state bool) error {
-	components, ok, err := unstructured.NestedSlice(m.Object, "spec", "overrides", "components")
-	if !ok {
-		return fmt.Errorf("failed to get spec.components in %v", m)
-	}
-	if err != nil {
-		return err
-	}
-	idx := -1
-	elem := map[string]interface{}{
-		"enabled": state,
-		"name":    "managedserviceaccount",
-	}
-	for i, c := range components {
-		component, ok := c.(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("unexpected format for component %v expecting it to be a map[string]interface{}", component)
-		}
-		if name, ok := component["name"]; ok && name == elem["name"] {
-			idx = i
-		}
-	}
-	// modify components
-	if idx < 0 {
-		// append
-		components = append(components, elem)
-	} else {
-		// update
-		components[idx] = elem
-	}
-	err = unstructured.SetNestedSlice(m.Object, components, "spec", "overrides", "components")
-	if err != nil {
-		return err
-	}
-	return nil
+func DisableManagedServiceAccountFeature(hubClient dynamic.Interface) error {
+	return featureInstaller.Disable(hubClient)
+}
+
+func IsManagedServiceAccountFeatureEnabled(hubClient dynamic.Interface) (bool, error) {
+	return featureInstaller.IsEnabled(hubClient)
 }
 
 func GetManagedServiceAccountAddon(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
 ) (*addonv1alpha1.ManagedClusterAddOn, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "addon.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedclusteraddons",
-	}
-
-	uManagedServiceAccountAddon, err := hubClient.Resource(gvr).Namespace(managedCluster.Name).
+	uManagedServiceAccountAddon, err := hubClient.Resource(gvrManagedClusterAddon).Namespace(managedCluster.Name).
 		Get(context.TODO(), "managed-serviceaccount", metav1.GetOptions{})
 	if err != nil {
 		return nil, err
@@ -180,14 +173,47 @@ func GetManagedServiceAccountAddon(
 	return managedServiceAccountAddon, nil
 }
 
+// RecreateManagedServiceAccountAddon creates the addon again from the spec, labels and annotations
+// of a ManagedClusterAddOn read before it was deleted
+func RecreateManagedServiceAccountAddon(
+	hubClient dynamic.Interface,
+	addon *addonv1alpha1.ManagedClusterAddOn,
+) (*addonv1alpha1.ManagedClusterAddOn, error) {
+	restored := &addonv1alpha1.ManagedClusterAddOn{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ManagedClusterAddOn",
+			APIVersion: "addon.open-cluster-management.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        addon.Name,
+			Namespace:   addon.Namespace,
+			Labels:      addon.Labels,
+			Annotations: addon.Annotations,
+		},
+		Spec: addon.Spec,
+	}
+	uRestored, err := toUnstructured(restored)
+	if err != nil {
+		return nil, err
+	}
+
+	uAddon, err := hubClient.Resource(gvrManagedClusterAddon).Namespace(addon.Namespace).
+		Create(context.TODO(), uRestored, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return unstructuredToManagedClusterAddon(uAddon)
+}
+
+// CreateManagedServiceAccountAddon installs the addon agent into installNamespace,
+// empty uses DefaultManagedServiceAccountInstallNamespace
 func CreateManagedServiceAccountAddon(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
+	installNamespace string,
 ) (*addonv1alpha1.ManagedClusterAddOn, error) {
-	gvr := schema.GroupVersionResource{
-		Group:    "addon.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedclusteraddons",
+	if installNamespace == "" {
+		installNamespace = DefaultManagedServiceAccountInstallNamespace
 	}
 
 	managedServiceAccountAddon, err := GetManagedServiceAccountAddon(hubClient, managedCluster)
@@ -202,7 +228,7 @@ func CreateManagedServiceAccountAddon(
 				Namespace: managedCluster.Name,
 			},
 			Spec: addonv1alpha1.ManagedClusterAddOnSpec{
-				InstallNamespace: "open-cluster-management-managed-serviceaccount",
+				InstallNamespace: installNamespace,
 			},
 		}
 
@@ -212,7 +238,7 @@ func CreateManagedServiceAccountAddon(
 		}
 
 		uManagedServiceAccountAddon, err := hubClient.
-			Resource(gvr).
+			Resource(gvrManagedClusterAddon).
 			Namespace(managedCluster.Name).
 			Create(
 				context.TODO(),
@@ -239,13 +265,7 @@ func DeleteManagedServiceAccountAddon(
 	hubClient dynamic.Interface,
 	managedCluster *clusterv1.ManagedCluster,
 ) error {
-	gvr := schema.GroupVersionResource{
-		Group:    "addon.open-cluster-management.io",
-		Version:  "v1alpha1",
-		Resource: "managedclusteraddons",
-	}
-
-	err := hubClient.Resource(gvr).Namespace(managedCluster.Name).Delete(
+	err := hubClient.Resource(gvrManagedClusterAddon).Namespace(managedCluster.Name).Delete(
 		context.TODO(),
 		"managed-serviceaccount",
 		metav1.DeleteOptions{},
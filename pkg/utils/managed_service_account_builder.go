@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	msav1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
+)
+
+// ManagedServiceAccountOption customizes the ManagedServiceAccount built by NewManagedServiceAccount
+type ManagedServiceAccountOption func(*msav1beta1.ManagedServiceAccount)
+
+// NewManagedServiceAccount builds a ManagedServiceAccount in the managed cluster namespace.
+// Without options it has rotation enabled, a one hour validity and the "e2e-" name prefix.
+func NewManagedServiceAccount(
+	managedCluster *clusterv1.ManagedCluster,
+	opts ...ManagedServiceAccountOption,
+) *msav1beta1.ManagedServiceAccount {
+	managedServiceAccount := &msav1beta1.ManagedServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "e2e-",
+			Namespace:    managedCluster.Name,
+		},
+		Spec: msav1beta1.ManagedServiceAccountSpec{
+			Rotation: msav1beta1.ManagedServiceAccountRotation{
+				Enabled: true,
+				Validity: metav1.Duration{
+					Duration: time.Hour,
+				},
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(managedServiceAccount)
+	}
+
+	return managedServiceAccount
+}
+
+// WithName sets an explicit name instead of a generated one
+func WithName(name string) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		msa.Name = name
+		msa.GenerateName = ""
+	}
+}
+
+// WithGenerateName lets the hub generate the name from the prefix
+func WithGenerateName(namePrefix string) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		msa.Name = ""
+		msa.GenerateName = namePrefix
+	}
+}
+
+// WithRotationEnabled turns the token rotation on or off
+func WithRotationEnabled(enabled bool) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		msa.Spec.Rotation.Enabled = enabled
+	}
+}
+
+// WithRotationValidity sets how long each issued token is valid
+func WithRotationValidity(validity time.Duration) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		msa.Spec.Rotation.Validity = metav1.Duration{Duration: validity}
+	}
+}
+
+// WithLabels adds the labels, keeping the ones already set
+func WithLabels(labels map[string]string) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		if msa.Labels == nil {
+			msa.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			msa.Labels[k] = v
+		}
+	}
+}
+
+// WithAnnotations adds the annotations, keeping the ones already set
+func WithAnnotations(annotations map[string]string) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		if msa.Annotations == nil {
+			msa.Annotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			msa.Annotations[k] = v
+		}
+	}
+}
+
+// WithTTLSecondsAfterCreation makes the hub delete the ManagedServiceAccount once the ttl expires
+func WithTTLSecondsAfterCreation(ttl int32) ManagedServiceAccountOption {
+	return func(msa *msav1beta1.ManagedServiceAccount) {
+		msa.Spec.TTLSecondsAfterCreation = &ttl
+	}
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	msav1alpha1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1alpha1"
+	msav1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
+)
+
+const (
+	ManagedServiceAccountGroup = "authentication.open-cluster-management.io"
+
+	ManagedServiceAccountVersionV1alpha1 = "v1alpha1"
+	ManagedServiceAccountVersionV1beta1  = "v1beta1"
+
+	// ManagedServiceAccountVersionAll can be set as the apiVersion option to exercise every served version
+	ManagedServiceAccountVersionAll = "all"
+)
+
+// ManagedServiceAccountVersions are the ManagedServiceAccount API versions this suite knows how to convert
+var ManagedServiceAccountVersions = []string{
+	ManagedServiceAccountVersionV1alpha1,
+	ManagedServiceAccountVersionV1beta1,
+}
+
+// version used by the ManagedServiceAccount helpers, see SetManagedServiceAccountVersion.
+// hubs store v1beta1, so helpers called before the negotiation use it too
+var managedServiceAccountVersion = ManagedServiceAccountVersionV1beta1
+
+func GetManagedServiceAccountVersion() string {
+	return managedServiceAccountVersion
+}
+
+// SetManagedServiceAccountVersion changes the API version used by the ManagedServiceAccount helpers
+func SetManagedServiceAccountVersion(version string) error {
+	if !isKnownManagedServiceAccountVersion(version) {
+		return fmt.Errorf("unsupported ManagedServiceAccount version %q, expecting one of %v",
+			version, ManagedServiceAccountVersions)
+	}
+	managedServiceAccountVersion = version
+	return nil
+}
+
+func isKnownManagedServiceAccountVersion(version string) bool {
+	for _, v := range ManagedServiceAccountVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func managedServiceAccountGVR(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    ManagedServiceAccountGroup,
+		Version:  version,
+		Resource: "managedserviceaccounts",
+	}
+}
+
+// GetServedManagedServiceAccountVersions asks the discovery API which ManagedServiceAccount
+// versions the hub serves, it returns the served versions and the preferred one
+func GetServedManagedServiceAccountVersions(
+	discoveryClient discovery.DiscoveryInterface,
+) ([]string, string, error) {
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name != ManagedServiceAccountGroup {
+			continue
+		}
+
+		versions := []string{}
+		for _, v := range group.Versions {
+			versions = append(versions, v.Version)
+		}
+		return versions, group.PreferredVersion.Version, nil
+	}
+
+	return nil, "", fmt.Errorf("api group %s is not served by the hub", ManagedServiceAccountGroup)
+}
+
+// NegotiateManagedServiceAccountVersions returns the ManagedServiceAccount versions the suite
+// should exercise given the requested version, and makes the first one the helpers default.
+// requested can be empty (preferred version), a specific version, or ManagedServiceAccountVersionAll.
+func NegotiateManagedServiceAccountVersions(
+	discoveryClient discovery.DiscoveryInterface,
+	requested string,
+) ([]string, error) {
+	served, preferred, err := GetServedManagedServiceAccountVersions(discoveryClient)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []string{}
+	switch requested {
+	case "":
+		selected = append(selected, preferred)
+	case ManagedServiceAccountVersionAll:
+		// preferred version first so it becomes the default
+		selected = append(selected, preferred)
+		for _, v := range served {
+			if v != preferred && isKnownManagedServiceAccountVersion(v) {
+				selected = append(selected, v)
+			}
+		}
+	default:
+		found := false
+		for _, v := range served {
+			if v == requested {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("ManagedServiceAccount version %s is not served by the hub, served versions: %v",
+				requested, served)
+		}
+		selected = append(selected, requested)
+	}
+
+	if err := SetManagedServiceAccountVersion(selected[0]); err != nil {
+		return nil, err
+	}
+
+	return selected, nil
+}
+
+// unstructuredToManagedServiceAccount decodes the object according to its apiVersion
+// and converts it to the v1beta1 type used across the helpers
+func unstructuredToManagedServiceAccount(
+	u *unstructured.Unstructured,
+) (*msav1beta1.ManagedServiceAccount, error) {
+	switch u.GroupVersionKind().Version {
+	case ManagedServiceAccountVersionV1alpha1:
+		msa := &msav1alpha1.ManagedServiceAccount{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+			u.UnstructuredContent(),
+			msa,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return convertV1alpha1ToV1beta1(msa), nil
+	case ManagedServiceAccountVersionV1beta1:
+		msa := &msav1beta1.ManagedServiceAccount{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+			u.UnstructuredContent(),
+			msa,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return msa, nil
+	default:
+		return nil, fmt.Errorf("unsupported ManagedServiceAccount apiVersion %s", u.GetAPIVersion())
+	}
+}
+
+// managedServiceAccountToUnstructured converts the v1beta1 object to the requested version
+func managedServiceAccountToUnstructured(
+	msa *msav1beta1.ManagedServiceAccount,
+	version string,
+) (*unstructured.Unstructured, error) {
+	switch version {
+	case ManagedServiceAccountVersionV1alpha1:
+		return toUnstructured(convertV1beta1ToV1alpha1(msa))
+	case ManagedServiceAccountVersionV1beta1:
+		out := msa.DeepCopy()
+		out.APIVersion = ManagedServiceAccountGroup + "/" + ManagedServiceAccountVersionV1beta1
+		out.Kind = "ManagedServiceAccount"
+		return toUnstructured(out)
+	default:
+		return nil, fmt.Errorf("unsupported ManagedServiceAccount version %s", version)
+	}
+}
+
+func convertV1alpha1ToV1beta1(in *msav1alpha1.ManagedServiceAccount) *msav1beta1.ManagedServiceAccount {
+	out := &msav1beta1.ManagedServiceAccount{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+		Spec: msav1beta1.ManagedServiceAccountSpec{
+			Rotation: msav1beta1.ManagedServiceAccountRotation{
+				Enabled:  in.Spec.Rotation.Enabled,
+				Validity: in.Spec.Rotation.Validity,
+			},
+			TTLSecondsAfterCreation: in.Spec.TTLSecondsAfterCreation,
+		},
+		Status: msav1beta1.ManagedServiceAccountStatus{
+			Conditions:          in.Status.Conditions,
+			ExpirationTimestamp: in.Status.ExpirationTimestamp,
+		},
+	}
+	out.APIVersion = ManagedServiceAccountGroup + "/" + ManagedServiceAccountVersionV1beta1
+	out.Kind = "ManagedServiceAccount"
+	if in.Status.TokenSecretRef != nil {
+		out.Status.TokenSecretRef = &msav1beta1.SecretRef{
+			Name:                 in.Status.TokenSecretRef.Name,
+			LastRefreshTimestamp: in.Status.TokenSecretRef.LastRefreshTimestamp,
+		}
+	}
+	return out
+}
+
+func convertV1beta1ToV1alpha1(in *msav1beta1.ManagedServiceAccount) *msav1alpha1.ManagedServiceAccount {
+	out := &msav1alpha1.ManagedServiceAccount{
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+		Spec: msav1alpha1.ManagedServiceAccountSpec{
+			Rotation: msav1alpha1.ManagedServiceAccountRotation{
+				Enabled:  in.Spec.Rotation.Enabled,
+				Validity: in.Spec.Rotation.Validity,
+			},
+			TTLSecondsAfterCreation: in.Spec.TTLSecondsAfterCreation,
+		},
+		Status: msav1alpha1.ManagedServiceAccountStatus{
+			Conditions:          in.Status.Conditions,
+			ExpirationTimestamp: in.Status.ExpirationTimestamp,
+		},
+	}
+	out.APIVersion = ManagedServiceAccountGroup + "/" + ManagedServiceAccountVersionV1alpha1
+	out.Kind = "ManagedServiceAccount"
+	if in.Status.TokenSecretRef != nil {
+		out.Status.TokenSecretRef = &msav1alpha1.SecretRef{
+			Name:                 in.Status.TokenSecretRef.Name,
+			LastRefreshTimestamp: in.Status.TokenSecretRef.LastRefreshTimestamp,
+		}
+	}
+	return out
+}
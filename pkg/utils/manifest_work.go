@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+var gvrManifestWork = schema.GroupVersionResource{
+	Group:    "work.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "manifestworks",
+}
+
+func unstructuredToManifestWork(
+	u *unstructured.Unstructured,
+) (*workv1.ManifestWork, error) {
+	work := &workv1.ManifestWork{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		work,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return work, nil
+}
+
+// NewManifestWork wraps the objects in a ManifestWork for the managed cluster,
+// the objects must have their TypeMeta set
+func NewManifestWork(
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+	objects ...runtime.Object,
+) *workv1.ManifestWork {
+	manifests := []workv1.Manifest{}
+	for _, obj := range objects {
+		manifests = append(manifests, workv1.Manifest{
+			RawExtension: runtime.RawExtension{Object: obj},
+		})
+	}
+
+	return &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ManifestWork",
+			APIVersion: "work.open-cluster-management.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: managedCluster.Name,
+		},
+		Spec: workv1.ManifestWorkSpec{
+			Workload: workv1.ManifestsTemplate{
+				Manifests: manifests,
+			},
+		},
+	}
+}
+
+func GetManifestWork(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) (*workv1.ManifestWork, error) {
+	uManifestWork, err := hubClient.Resource(gvrManifestWork).
+		Namespace(managedCluster.Name).
+		Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManifestWork(uManifestWork)
+}
+
+func CreateManifestWork(
+	hubClient dynamic.Interface,
+	work *workv1.ManifestWork,
+) (*workv1.ManifestWork, error) {
+	uNewManifestWork, err := toUnstructured(work)
+	if err != nil {
+		return nil, err
+	}
+
+	uManifestWork, err := hubClient.Resource(gvrManifestWork).
+		Namespace(work.Namespace).
+		Create(context.TODO(), uNewManifestWork, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManifestWork(uManifestWork)
+}
+
+// UpdateManifestWork replaces the workload of the existing ManifestWork with the given one
+func UpdateManifestWork(
+	hubClient dynamic.Interface,
+	work *workv1.ManifestWork,
+) (*workv1.ManifestWork, error) {
+	uNewManifestWork, err := toUnstructured(work)
+	if err != nil {
+		return nil, err
+	}
+
+	uManifestWork, err := hubClient.Resource(gvrManifestWork).
+		Namespace(work.Namespace).
+		Update(context.TODO(), uNewManifestWork, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManifestWork(uManifestWork)
+}
+
+func DeleteManifestWork(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) error {
+	return hubClient.Resource(gvrManifestWork).
+		Namespace(managedCluster.Name).
+		Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// IsManifestWorkAvailable returns true once the current generation of the ManifestWork
+// is applied and all its resources exist on the managed cluster
+func IsManifestWorkAvailable(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) bool {
+	work, err := GetManifestWork(hubClient, managedCluster, name)
+	if err != nil {
+		return false
+	}
+
+	applied := false
+	available := false
+	for _, condition := range work.Status.Conditions {
+		if condition.ObservedGeneration != work.Generation || condition.Status != metav1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case workv1.WorkApplied:
+			applied = true
+		case workv1.WorkAvailable:
+			available = true
+		}
+	}
+
+	return applied && available
+}
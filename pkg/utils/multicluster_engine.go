@@ -0,0 +1,348 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// managedServiceAccountComponent is the name of the feature in spec.overrides.components of the MCE
+	managedServiceAccountComponent = "managedserviceaccount"
+
+	// FieldManager identifies the writes of the suite on shared objects
+	FieldManager = "managed-serviceaccount-e2e"
+)
+
+// jsonPatchOperation is one operation of a RFC 6902 JSON patch
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON always writes the value, an empty list, false or "" included, except for remove which takes none
+func (o jsonPatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(map[string]string{"op": o.Op, "path": o.Path})
+	}
+
+	type operation jsonPatchOperation
+	return json.Marshal(operation(o))
+}
+
+// FeatureSyncTimeout is how long the MCE may take to reflect a change made through the MCH
+var FeatureSyncTimeout = time.Minute * 2
+
+// FeatureSettleTime is how long a direct MCE edit has to survive on a hub with an MCH
+var FeatureSettleTime = time.Second * 30
+
+// ReconciledAwayError is returned when the MCH operator reverts a direct edit of the MCE component
+// and the MCH does not accept the component either
+type ReconciledAwayError struct {
+	MultiClusterHub    string
+	MultiClusterEngine string
+	Enabled            bool
+}
+
+func (e *ReconciledAwayError) Error() string {
+	return fmt.Sprintf("MultiClusterHub %s reconciled the %s component of MultiClusterEngine %s away from enabled=%t, "+
+		"and it does not accept the component in its own spec.overrides.components",
+		e.MultiClusterHub, managedServiceAccountComponent, e.MultiClusterEngine, e.Enabled)
+}
+
+// GetManagingMultiClusterHub returns the MCH that installed the MCE, nil on a standalone MCE hub
+// and when the MCH of the hub does not own the MCE, the MCE is then patched directly
+func GetManagingMultiClusterHub(
+	hubClient dynamic.Interface,
+	mce *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	mch, err := GetMultiClusterHub(hubClient)
+	if err != nil || mch == nil {
+		return nil, err
+	}
+
+	if !isManagedByMultiClusterHub(mce, mch) {
+		return nil, nil
+	}
+	return mch, nil
+}
+
+// isManagedByMultiClusterHub tells whether the MCE carries the installer labels the MCH operator sets on
+// the MCE it installs, or an owner reference to the MCH
+func isManagedByMultiClusterHub(mce *unstructured.Unstructured, mch *unstructured.Unstructured) bool {
+	labels := mce.GetLabels()
+	if labels["installer.name"] == mch.GetName() && labels["installer.namespace"] == mch.GetNamespace() {
+		return true
+	}
+	for _, owner := range mce.GetOwnerReferences() {
+		if owner.Kind == "MultiClusterHub" && owner.UID == mch.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetManagedServiceAccountFeature enables or disables the managedserviceaccount component, the other
+// fields of the entry are kept. On a standalone MCE hub the MCE is patched. When an MCH manages the MCE
+// the MCH is patched if its webhook accepts the component, and the MCE is expected to follow. Otherwise
+// the MCE is patched and the edit has to survive the MCH operator, a *ReconciledAwayError is returned when it does not
+func SetManagedServiceAccountFeature(hubClient dynamic.Interface, enabled bool) error {
+	mutate := func(current map[string]interface{}) map[string]interface{} {
+		if current == nil {
+			current = map[string]interface{}{"name": managedServiceAccountComponent}
+		}
+		current["enabled"] = enabled
+		return current
+	}
+
+	mce, err := GetMultiClusterEngine(hubClient)
+	if err != nil {
+		return err
+	}
+	// nothing to write, and nothing for the MCH to reconcile away
+	if current, err := isMultiClusterEngineFeatureEnabled(hubClient); err == nil && current == enabled {
+		return nil
+	}
+
+	mch, err := GetManagingMultiClusterHub(hubClient, mce)
+	if err != nil {
+		return err
+	}
+	if mch == nil {
+		return patchMultiClusterEngineComponent(hubClient, managedServiceAccountComponent, mutate)
+	}
+
+	mchResource := hubClient.Resource(gvrMCH).Namespace(mch.GetNamespace())
+	getMCH := func() (*unstructured.Unstructured, error) {
+		return mchResource.Get(context.TODO(), mch.GetName(), metav1.GetOptions{})
+	}
+
+	// the MCH webhook rejects the components it does not manage, ask it before writing
+	if patchComponent(mchResource, getMCH, managedServiceAccountComponent, mutate, true) == nil {
+		err = patchComponent(mchResource, getMCH, managedServiceAccountComponent, mutate, false)
+		if err != nil {
+			return err
+		}
+		return waitForManagedServiceAccountFeature(hubClient, enabled, FeatureSyncTimeout, func() error {
+			return fmt.Errorf("MultiClusterEngine %s does not reflect %s enabled=%t set through MultiClusterHub %s/%s after %v",
+				mce.GetName(), managedServiceAccountComponent, enabled, mch.GetNamespace(), mch.GetName(), FeatureSyncTimeout)
+		})
+	}
+
+	err = patchMultiClusterEngineComponent(hubClient, managedServiceAccountComponent, mutate)
+	if err != nil {
+		return err
+	}
+	return verifyManagedServiceAccountFeatureSettles(hubClient, enabled, FeatureSettleTime, &ReconciledAwayError{
+		MultiClusterHub:    mch.GetNamespace() + "/" + mch.GetName(),
+		MultiClusterEngine: mce.GetName(),
+		Enabled:            enabled,
+	})
+}
+
+// waitForManagedServiceAccountFeature waits for the MCE component to be enabled or disabled,
+// timedOut builds the error returned when it is not in time
+func waitForManagedServiceAccountFeature(
+	hubClient dynamic.Interface,
+	enabled bool,
+	timeout time.Duration,
+	timedOut func() error,
+) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := isMultiClusterEngineFeatureEnabled(hubClient)
+		if err == nil && current == enabled {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return timedOut()
+		}
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// verifyManagedServiceAccountFeatureSettles reads the MCE component until settle has elapsed,
+// and returns reverted as soon as it is seen with the other value
+func verifyManagedServiceAccountFeatureSettles(
+	hubClient dynamic.Interface,
+	enabled bool,
+	settle time.Duration,
+	reverted error,
+) error {
+	deadline := time.Now().Add(settle)
+	for time.Now().Before(deadline) {
+		time.Sleep(WaitPollInterval)
+
+		current, err := isMultiClusterEngineFeatureEnabled(hubClient)
+		if err == nil && current != enabled {
+			return reverted
+		}
+	}
+	return nil
+}
+
+// isMultiClusterEngineFeatureEnabled reads the managedserviceaccount component of the MCE,
+// a missing entry is reported as disabled
+func isMultiClusterEngineFeatureEnabled(hubClient dynamic.Interface) (bool, error) {
+	mce, err := GetMultiClusterEngine(hubClient)
+	if err != nil {
+		return false, err
+	}
+	component, err := getComponent(mce, managedServiceAccountComponent)
+	if err != nil || component == nil {
+		return false, err
+	}
+	enabled, _ := component["enabled"].(bool)
+	return enabled, nil
+}
+
+// getComponent returns a copy of the named entry of spec.overrides.components, nil when there is none
+func getComponent(m *unstructured.Unstructured, name string) (map[string]interface{}, error) {
+	components, _, err := unstructured.NestedSlice(m.Object, "spec", "overrides", "components")
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range components {
+		component, ok := c.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected format for component %v expecting it to be a map[string]interface{}", c)
+		}
+		if component["name"] == name {
+			return runtime.DeepCopyJSON(component), nil
+		}
+	}
+	return nil, nil
+}
+
+// patchMultiClusterEngineComponent rewrites one entry of spec.overrides.components of the MCE,
+// mutate gets a copy of the entry (nil when missing) and returns the wanted entry (nil to remove it)
+func patchMultiClusterEngineComponent(
+	hubClient dynamic.Interface,
+	name string,
+	mutate func(map[string]interface{}) map[string]interface{},
+) error {
+	return patchComponent(
+		hubClient.Resource(gvrMCE),
+		func() (*unstructured.Unstructured, error) { return GetMultiClusterEngine(hubClient) },
+		name,
+		mutate,
+		false,
+	)
+}
+
+// patchComponent rewrites one entry of spec.overrides.components of the object returned by get with
+// a JSON patch. components is a plain list to the apiserver, a server-side apply would take over the
+// whole list, so the patch tests the list it was computed from instead, and is computed again when
+// an operator wrote in between. dryRun only asks the apiserver and its webhooks whether the patch is accepted
+func patchComponent(
+	resource dynamic.ResourceInterface,
+	get func() (*unstructured.Unstructured, error),
+	name string,
+	mutate func(map[string]interface{}) map[string]interface{},
+	dryRun bool,
+) error {
+	// a failed test operation is rejected as invalid rather than as a conflict
+	retriable := func(err error) bool {
+		return errors.IsConflict(err) || errors.IsInvalid(err)
+	}
+
+	options := metav1.PatchOptions{FieldManager: FieldManager}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		obj, err := get()
+		if err != nil {
+			return err
+		}
+
+		operations, err := componentPatch(obj, name, mutate)
+		if err != nil || len(operations) == 0 {
+			return err
+		}
+
+		patch, err := json.Marshal(operations)
+		if err != nil {
+			return err
+		}
+
+		_, err = resource.Patch(context.TODO(), obj.GetName(), types.JSONPatchType, patch, options)
+		return err
+	})
+}
+
+// componentPatch computes the JSON patch operations that turn the component entry of the MCE or MCH
+// into the mutated one, no operation is returned when there is nothing to change
+func componentPatch(
+	obj *unstructured.Unstructured,
+	name string,
+	mutate func(map[string]interface{}) map[string]interface{},
+) ([]jsonPatchOperation, error) {
+	components, foundComponents, err := unstructured.NestedSlice(obj.Object, "spec", "overrides", "components")
+	if err != nil {
+		return nil, err
+	}
+
+	idx := -1
+	var current map[string]interface{}
+	for i, c := range components {
+		component, ok := c.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected format for component %v expecting it to be a map[string]interface{}", c)
+		}
+		if component["name"] == name {
+			idx = i
+			current = runtime.DeepCopyJSON(component)
+		}
+	}
+
+	var wanted map[string]interface{}
+	if current != nil {
+		wanted = mutate(runtime.DeepCopyJSON(current))
+	} else {
+		wanted = mutate(nil)
+	}
+	if reflect.DeepEqual(current, wanted) {
+		return nil, nil
+	}
+
+	if !foundComponents {
+		// a path that does not exist cannot be tested, guard with the resourceVersion instead
+		operations := []jsonPatchOperation{
+			{Op: "test", Path: "/metadata/resourceVersion", Value: obj.GetResourceVersion()},
+		}
+		if _, foundOverrides, _ := unstructured.NestedMap(obj.Object, "spec", "overrides"); foundOverrides {
+			return append(operations, jsonPatchOperation{
+				Op: "add", Path: "/spec/overrides/components", Value: []interface{}{wanted},
+			}), nil
+		}
+		return append(operations, jsonPatchOperation{
+			Op: "add", Path: "/spec/overrides", Value: map[string]interface{}{"components": []interface{}{wanted}},
+		}), nil
+	}
+
+	operations := []jsonPatchOperation{
+		{Op: "test", Path: "/spec/overrides/components", Value: components},
+	}
+	switch {
+	case idx < 0:
+		operations = append(operations, jsonPatchOperation{Op: "add", Path: "/spec/overrides/components/-", Value: wanted})
+	case wanted == nil:
+		operations = append(operations, jsonPatchOperation{Op: "remove", Path: fmt.Sprintf("/spec/overrides/components/%d", idx)})
+	default:
+		operations = append(operations, jsonPatchOperation{Op: "replace", Path: fmt.Sprintf("/spec/overrides/components/%d", idx), Value: wanted})
+	}
+	return operations, nil
+}
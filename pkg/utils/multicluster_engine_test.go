@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newMultiClusterEngine(components []interface{}) *unstructured.Unstructured {
+	mce := &unstructured.Unstructured{}
+	mce.SetAPIVersion("multicluster.openshift.io/v1")
+	mce.SetKind("MultiClusterEngine")
+	mce.SetName("multiclusterengine")
+	mce.SetResourceVersion("1")
+	_ = unstructured.SetNestedSlice(mce.Object, components, "spec", "overrides", "components")
+	return mce
+}
+
+func TestJSONPatchOperationKeepsEmptyValues(t *testing.T) {
+	cases := []struct {
+		operation jsonPatchOperation
+		expected  string
+	}{
+		{jsonPatchOperation{Op: "test", Path: "/a", Value: []interface{}{}}, `{"op":"test","path":"/a","value":[]}`},
+		{jsonPatchOperation{Op: "replace", Path: "/a", Value: false}, `{"op":"replace","path":"/a","value":false}`},
+		{jsonPatchOperation{Op: "test", Path: "/a", Value: ""}, `{"op":"test","path":"/a","value":""}`},
+		{jsonPatchOperation{Op: "test", Path: "/a", Value: nil}, `{"op":"test","path":"/a","value":null}`},
+		{jsonPatchOperation{Op: "remove", Path: "/a"}, `{"op":"remove","path":"/a"}`},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(c.operation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, data)
+		}
+	}
+}
+
+func TestComponentPatchOnEmptyComponents(t *testing.T) {
+	mce := newMultiClusterEngine([]interface{}{})
+
+	operations, err := componentPatch(mce, managedServiceAccountComponent, func(map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"name": managedServiceAccountComponent, "enabled": true}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(patch), `"op":"test","path":"/spec/overrides/components","value":[]`) {
+		t.Fatalf("the test operation lost the empty components list: %s", patch)
+	}
+
+	// the apiserver applies JSON patches with the same library
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mce.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = decoded.Apply(data); err != nil {
+		t.Fatal(err)
+	}
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	component, err := getComponent(patched, managedServiceAccountComponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := component["enabled"].(bool); !enabled {
+		t.Errorf("expected the component to be enabled, got %v", component)
+	}
+}
+
+func TestIsManagedByMultiClusterHub(t *testing.T) {
+	mch := &unstructured.Unstructured{}
+	mch.SetKind("MultiClusterHub")
+	mch.SetName("multiclusterhub")
+	mch.SetNamespace("open-cluster-management")
+	mch.SetUID("mch-uid")
+
+	labelled := newMultiClusterEngine(nil)
+	labelled.SetLabels(map[string]string{"installer.name": "multiclusterhub", "installer.namespace": "open-cluster-management"})
+	owned := newMultiClusterEngine(nil)
+	owned.SetOwnerReferences([]metav1.OwnerReference{{Kind: "MultiClusterHub", Name: "multiclusterhub", UID: "mch-uid"}})
+	otherInstaller := newMultiClusterEngine(nil)
+	otherInstaller.SetLabels(map[string]string{"installer.name": "other", "installer.namespace": "open-cluster-management"})
+	standalone := newMultiClusterEngine(nil)
+
+	cases := map[string]struct {
+		mce      *unstructured.Unstructured
+		expected bool
+	}{
+		"installer labels":         {labelled, true},
+		"owner reference":          {owned, true},
+		"labels of another MCH":    {otherInstaller, false},
+		"no ownership of any kind": {standalone, false},
+	}
+	for name, c := range cases {
+		if managed := isManagedByMultiClusterHub(c.mce, mch); managed != c.expected {
+			t.Errorf("%s: expected %v, got %v", name, c.expected, managed)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+var gvrNode = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "nodes",
+}
+
+// SetNodesLabel sets the label on every node of the managed cluster, an empty value removes it
+func SetNodesLabel(
+	mcDynClient dynamic.Interface,
+	key string,
+	value string,
+) error {
+	var labelValue interface{} = value
+	if value == "" {
+		labelValue = nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{key: labelValue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	uNodeList, err := mcDynClient.Resource(gvrNode).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, uNode := range uNodeList.Items {
+		_, err := mcDynClient.Resource(gvrNode).
+			Patch(context.TODO(), uNode.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"context"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+)
+
+var gvrPlacement = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "placements",
+}
+var gvrPlacementDecision = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1beta1",
+	Resource: "placementdecisions",
+}
+var gvrManagedClusterSetBinding = schema.GroupVersionResource{
+	Group:    "cluster.open-cluster-management.io",
+	Version:  "v1beta2",
+	Resource: "managedclustersetbindings",
+}
+
+func unstructuredToPlacementDecision(
+	u *unstructured.Unstructured,
+) (*clusterv1beta1.PlacementDecision, error) {
+	decision := &clusterv1beta1.PlacementDecision{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		decision,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// CreateManagedClusterSetBinding makes the clusters of the ManagedClusterSet selectable
+// by the Placements of the namespace
+func CreateManagedClusterSetBinding(
+	hubClient dynamic.Interface,
+	namespace string,
+	clusterSet string,
+) error {
+	binding := &clusterv1beta2.ManagedClusterSetBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ManagedClusterSetBinding",
+			APIVersion: "cluster.open-cluster-management.io/v1beta2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			// the binding has to be named after the set
+			Name:      clusterSet,
+			Namespace: namespace,
+		},
+		Spec: clusterv1beta2.ManagedClusterSetBindingSpec{
+			ClusterSet: clusterSet,
+		},
+	}
+
+	uBinding, err := toUnstructured(binding)
+	if err != nil {
+		return err
+	}
+
+	_, err = hubClient.Resource(gvrManagedClusterSetBinding).
+		Namespace(namespace).
+		Create(context.TODO(), uBinding, metav1.CreateOptions{})
+	return err
+}
+
+// NewPlacement builds a Placement selecting the clusters of the bound sets that carry the labels
+func NewPlacement(
+	namespace string,
+	name string,
+	matchLabels map[string]string,
+) *clusterv1beta1.Placement {
+	return &clusterv1beta1.Placement{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Placement",
+			APIVersion: "cluster.open-cluster-management.io/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: clusterv1beta1.PlacementSpec{
+			Predicates: []clusterv1beta1.ClusterPredicate{
+				{
+					RequiredClusterSelector: clusterv1beta1.ClusterSelector{
+						LabelSelector: metav1.LabelSelector{
+							MatchLabels: matchLabels,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func CreatePlacement(
+	hubClient dynamic.Interface,
+	placement *clusterv1beta1.Placement,
+) error {
+	uPlacement, err := toUnstructured(placement)
+	if err != nil {
+		return err
+	}
+
+	_, err = hubClient.Resource(gvrPlacement).
+		Namespace(placement.Namespace).
+		Create(context.TODO(), uPlacement, metav1.CreateOptions{})
+	return err
+}
+
+func DeletePlacement(
+	hubClient dynamic.Interface,
+	namespace string,
+	name string,
+) error {
+	return hubClient.Resource(gvrPlacement).
+		Namespace(namespace).
+		Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// GetPlacementDecisionClusters returns the sorted names of the clusters selected by the Placement,
+// across all of its PlacementDecisions
+func GetPlacementDecisionClusters(
+	hubClient dynamic.Interface,
+	namespace string,
+	placementName string,
+) ([]string, error) {
+	uDecisionList, err := hubClient.Resource(gvrPlacementDecision).
+		Namespace(namespace).
+		List(context.TODO(), metav1.ListOptions{
+			LabelSelector: clusterv1beta1.PlacementLabel + "=" + placementName,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []string{}
+	for i := range uDecisionList.Items {
+		decision, err := unstructuredToPlacementDecision(&uDecisionList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range decision.Status.Decisions {
+			clusters = append(clusters, d.ClusterName)
+		}
+	}
+	sort.Strings(clusters)
+
+	return clusters, nil
+}
+
+// SetManagedServiceAccountAddonInstallStrategy replaces the install strategy of the managed-serviceaccount
+// ClusterManagementAddOn and returns the strategy it replaced
+func SetManagedServiceAccountAddonInstallStrategy(
+	hubClient dynamic.Interface,
+	strategy addonv1alpha1.InstallStrategy,
+) (addonv1alpha1.InstallStrategy, error) {
+	var previous addonv1alpha1.InstallStrategy
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterManagementAddon, err := GetManagedServiceAccountClusterManagementAddon(hubClient)
+		if err != nil {
+			return err
+		}
+
+		previous = clusterManagementAddon.Spec.InstallStrategy
+		clusterManagementAddon.Spec.InstallStrategy = strategy
+
+		uClusterManagementAddon, err := toUnstructured(clusterManagementAddon)
+		if err != nil {
+			return err
+		}
+
+		_, err = hubClient.Resource(gvrClusterManagementAddon).
+			Update(context.TODO(), uClusterManagementAddon, metav1.UpdateOptions{})
+		return err
+	})
+	return previous, err
+}
+
+// NewPlacementsInstallStrategy installs the addon on the clusters selected by the Placements
+func NewPlacementsInstallStrategy(placements ...*clusterv1beta1.Placement) addonv1alpha1.InstallStrategy {
+	strategy := addonv1alpha1.InstallStrategy{
+		Type: addonv1alpha1.AddonInstallStrategyPlacements,
+	}
+	for _, placement := range placements {
+		strategy.Placements = append(strategy.Placements, addonv1alpha1.PlacementStrategy{
+			PlacementRef: addonv1alpha1.PlacementRef{
+				Namespace: placement.Namespace,
+				Name:      placement.Name,
+			},
+		})
+	}
+	return strategy
+}
+
+// isOwnedByClusterManagementAddon tells the addons created by the install strategy from the ones created by hand
+func isOwnedByClusterManagementAddon(obj metav1.Object) bool {
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.Kind == "ClusterManagementAddOn" && owner.Name == "managed-serviceaccount" {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPlacedManagedServiceAccountAddonClusters returns the sorted names of the clusters with a
+// managed-serviceaccount ManagedClusterAddOn created by the install strategy, addons created
+// by hand are not owned by the ClusterManagementAddOn and are left out
+func ListPlacedManagedServiceAccountAddonClusters(
+	hubClient dynamic.Interface,
+) ([]string, error) {
+	uAddonList, err := hubClient.Resource(gvrManagedClusterAddon).
+		List(context.TODO(), metav1.ListOptions{
+			FieldSelector: "metadata.name=managed-serviceaccount",
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []string{}
+	for _, uAddon := range uAddonList.Items {
+		if uAddon.GetName() != "managed-serviceaccount" || uAddon.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if isOwnedByClusterManagementAddon(&uAddon) {
+			clusters = append(clusters, uAddon.GetNamespace())
+		}
+	}
+	sort.Strings(clusters)
+
+	return clusters, nil
+}
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// AccessCheck is one cell of a permission matrix, Allowed is the expected answer
+type AccessCheck struct {
+	authorizationv1.ResourceAttributes
+	Allowed bool
+}
+
+func (c AccessCheck) String() string {
+	resource := c.Resource
+	if c.Group != "" {
+		resource = c.Resource + "." + c.Group
+	}
+	target := c.Namespace + "/" + c.Name
+	return fmt.Sprintf("%s %s %s", c.Verb, resource, target)
+}
+
+// NewManagedServiceAccountRBACManifestWork builds a ManifestWork that delivers a Role with the rules
+// and a RoleBinding of that Role to the ServiceAccount, both named after the work
+func NewManagedServiceAccountRBACManifestWork(
+	managedCluster *clusterv1.ManagedCluster,
+	workName string,
+	serviceAccountNamespace string,
+	serviceAccountName string,
+	namespace string,
+	rules []rbacv1.PolicyRule,
+) *workv1.ManifestWork {
+	role := &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workName,
+			Namespace: namespace,
+		},
+		Rules: rules,
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workName,
+			Namespace: namespace,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "Role",
+			Name:     workName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      serviceAccountName,
+				Namespace: serviceAccountNamespace,
+			},
+		},
+	}
+
+	return NewManifestWork(managedCluster, workName, role, roleBinding)
+}
+
+// GrantManagedServiceAccountPermissions delivers the rules in the namespace of the managed cluster
+// to the ServiceAccount backing the ManagedServiceAccount, through a ManifestWork
+func GrantManagedServiceAccountPermissions(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	managedServiceAccountName string,
+	namespace string,
+	rules []rbacv1.PolicyRule,
+) (*workv1.ManifestWork, error) {
+	serviceAccountNamespace, err := GetManagedServiceAccountNamespace(hubClient, managedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	work := NewManagedServiceAccountRBACManifestWork(
+		managedCluster,
+		managedServiceAccountName+"-rbac",
+		serviceAccountNamespace,
+		managedServiceAccountName,
+		namespace,
+		rules,
+	)
+
+	return CreateManifestWork(hubClient, work)
+}
+
+// RemoveManagedServiceAccountRoleBinding drops the RoleBinding from the ManifestWork
+// while keeping the Role, the work agent then deletes the binding on the managed cluster
+func RemoveManagedServiceAccountRoleBinding(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	workName string,
+) error {
+	work, err := GetManifestWork(hubClient, managedCluster, workName)
+	if err != nil {
+		return err
+	}
+
+	manifests := []workv1.Manifest{}
+	for _, manifest := range work.Spec.Workload.Manifests {
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(manifest.Raw, &obj.Object); err != nil {
+			return err
+		}
+		if obj.GetKind() == "RoleBinding" {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	work.Spec.Workload.Manifests = manifests
+
+	_, err = UpdateManifestWork(hubClient, work)
+	return err
+}
+
+func unstructuredToSubjectAccessReview(
+	u *unstructured.Unstructured,
+) (*authorizationv1.SubjectAccessReview, error) {
+	sar := &authorizationv1.SubjectAccessReview{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		u.UnstructuredContent(),
+		sar,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sar, nil
+}
+
+// CreateSubjectAccessReview asks the apiserver whether the user is allowed to do the given action,
+// the client needs permission to create subjectaccessreviews
+func CreateSubjectAccessReview(
+	mcDynClient dynamic.Interface,
+	user string,
+	groups []string,
+	attributes authorizationv1.ResourceAttributes,
+) (*authorizationv1.SubjectAccessReview, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    "authorization.k8s.io",
+		Version:  "v1",
+		Resource: "subjectaccessreviews",
+	}
+
+	newSubjectAccessReview := &authorizationv1.SubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "SubjectAccessReview",
+			APIVersion: "authorization.k8s.io/v1",
+		},
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               user,
+			Groups:             groups,
+			ResourceAttributes: &attributes,
+		},
+	}
+
+	uNewSubjectAccessReview, err := toUnstructured(newSubjectAccessReview)
+	if err != nil {
+		return nil, err
+	}
+
+	uSubjectAccessReview, err := mcDynClient.Resource(gvr).Create(
+		context.TODO(),
+		uNewSubjectAccessReview,
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToSubjectAccessReview(uSubjectAccessReview)
+}
+
+// ServiceAccountGroups returns the groups the apiserver adds to a service account username
+func ServiceAccountGroups(username string) []string {
+	groups := []string{"system:serviceaccounts", "system:authenticated"}
+	parts := strings.Split(username, ":")
+	if len(parts) == 4 && parts[0] == "system" && parts[1] == "serviceaccount" {
+		groups = append(groups, "system:serviceaccounts:"+parts[2])
+	}
+	return groups
+}
+
+// VerifyAccessMatrix checks every expected answer with a SubjectAccessReview through the admin client
+// and, when tokenClient is not nil, with a SelfSubjectAccessReview made with the token itself.
+// the returned error lists every check that did not get the expected answer
+func VerifyAccessMatrix(
+	mcDynClient dynamic.Interface,
+	tokenClient dynamic.Interface,
+	username string,
+	checks []AccessCheck,
+) error {
+	mismatches := []string{}
+	for _, check := range checks {
+		sar, err := CreateSubjectAccessReview(mcDynClient, username, ServiceAccountGroups(username), check.ResourceAttributes)
+		if err != nil {
+			return err
+		}
+		if sar.Status.Allowed != check.Allowed {
+			mismatches = append(mismatches, fmt.Sprintf("SubjectAccessReview %s: allowed=%t, expected %t (%s)",
+				check, sar.Status.Allowed, check.Allowed, sar.Status.Reason))
+		}
+
+		if tokenClient == nil {
+			continue
+		}
+		ssar, err := CreateSelfSubjectAccessReview(tokenClient, check.ResourceAttributes)
+		if err != nil {
+			return err
+		}
+		if ssar.Status.Allowed != check.Allowed {
+			mismatches = append(mismatches, fmt.Sprintf("SelfSubjectAccessReview %s: allowed=%t, expected %t (%s)",
+				check, ssar.Status.Allowed, check.Allowed, ssar.Status.Reason))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("access matrix of %s does not match:\n%s", username, strings.Join(mismatches, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// TokenClaims are the JWT claims of a service account token, decoded without calling any server
+type TokenClaims struct {
+	Issuer    string        `json:"iss"`
+	Subject   string        `json:"sub"`
+	Audience  TokenAudience `json:"aud,omitempty"`
+	ExpiresAt int64         `json:"exp,omitempty"`
+	IssuedAt  int64         `json:"iat,omitempty"`
+	NotBefore int64         `json:"nbf,omitempty"`
+
+	// set on bound tokens issued by the TokenRequest api
+	Kubernetes *KubernetesTokenClaims `json:"kubernetes.io,omitempty"`
+
+	// set on legacy tokens read from a service account token secret
+	LegacyNamespace          string `json:"kubernetes.io/serviceaccount/namespace,omitempty"`
+	LegacySecretName         string `json:"kubernetes.io/serviceaccount/secret.name,omitempty"`
+	LegacyServiceAccountName string `json:"kubernetes.io/serviceaccount/service-account.name,omitempty"`
+}
+
+type KubernetesTokenClaims struct {
+	Namespace      string                `json:"namespace"`
+	ServiceAccount TokenObjectReference  `json:"serviceaccount"`
+	Pod            *TokenObjectReference `json:"pod,omitempty"`
+	Secret         *TokenObjectReference `json:"secret,omitempty"`
+}
+
+type TokenObjectReference struct {
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+}
+
+// TokenAudience accepts both forms of the aud claim, a single string or a list
+type TokenAudience []string
+
+func (a *TokenAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = TokenAudience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+func (c *TokenClaims) ExpirationTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+func (c *TokenClaims) IssuedAtTime() time.Time {
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// ServiceAccountNamespace returns the namespace claim of either bound or legacy tokens
+func (c *TokenClaims) ServiceAccountNamespace() string {
+	if c.Kubernetes != nil {
+		return c.Kubernetes.Namespace
+	}
+	return c.LegacyNamespace
+}
+
+// ServiceAccountName returns the service account claim of either bound or legacy tokens
+func (c *TokenClaims) ServiceAccountName() string {
+	if c.Kubernetes != nil {
+		return c.Kubernetes.ServiceAccount.Name
+	}
+	return c.LegacyServiceAccountName
+}
+
+// IsBoundToken tells whether the token comes from the TokenRequest api and expires,
+// as opposed to a legacy token that lives as long as its secret
+func (c *TokenClaims) IsBoundToken() bool {
+	return c.Kubernetes != nil && c.LegacySecretName == "" && c.ExpiresAt != 0
+}
+
+// ParseTokenClaims decodes the claims of a JWT, the signature is not verified
+func ParseTokenClaims(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a JWT, expecting 3 parts got %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode the JWT payload: %v", err)
+	}
+
+	claims := &TokenClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal the JWT claims: %v", err)
+	}
+
+	return claims, nil
+}
+
+// GetManagedServiceAccountTokenClaims reads the token from the hub secret and decodes its claims
+func GetManagedServiceAccountTokenClaims(
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) (*TokenClaims, error) {
+	token, err := GetManagedServiceAccountToken(hubClient, managedCluster, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTokenClaims(token)
+}
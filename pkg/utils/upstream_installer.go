@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// UpstreamInstallNamespace is where the upstream installer runs the addon manager
+	UpstreamInstallNamespace = "open-cluster-management-addon"
+	// UpstreamManagerDeploymentName is the addon manager Deployment of the upstream installer
+	UpstreamManagerDeploymentName = "managed-serviceaccount-addon-manager"
+)
+
+// upstreamManifests are applied in file name order and deleted in reverse order
+//
+//go:embed manifests/upstream/*.yaml
+var upstreamManifests embed.FS
+
+// upstreamManifestResources maps the kinds of the embedded manifests to their resources
+var upstreamManifestResources = map[string]schema.GroupVersionResource{
+	"CustomResourceDefinition": {Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+	"Namespace":                {Version: "v1", Resource: "namespaces"},
+	"ServiceAccount":           {Version: "v1", Resource: "serviceaccounts"},
+	"ClusterRole":              {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+	"ClusterRoleBinding":       {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	"Deployment":               {Group: "apps", Version: "v1", Resource: "deployments"},
+	"ClusterManagementAddOn":   gvrClusterManagementAddon,
+}
+
+// UpstreamAddonRemovalTimeout is how long Disable waits for the addons to be removed
+// while the addon manager is still running to clean up after them
+var UpstreamAddonRemovalTimeout = time.Minute * 5
+
+// UpstreamInstaller installs the open-cluster-management.io managed-serviceaccount release from
+// the embedded manifests, for hubs running the cluster-manager without MCE or MCH
+type UpstreamInstaller struct {
+	// Image of the addon manager and of the agent it deploys, empty keeps the released image of the manifests
+	Image string
+}
+
+func (i *UpstreamInstaller) Name() string {
+	return InstallModeUpstream
+}
+
+// Enable applies the manifests with server-side apply, nothing is written when the feature is already enabled
+func (i *UpstreamInstaller) Enable(hubClient dynamic.Interface) error {
+	if enabled, err := i.IsEnabled(hubClient); err == nil && enabled {
+		return nil
+	}
+
+	objs, err := i.manifests()
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		resource, err := upstreamResource(hubClient, obj)
+		if err != nil {
+			return err
+		}
+		_, err = resource.Apply(context.TODO(), obj.GetName(), obj, metav1.ApplyOptions{
+			FieldManager: FieldManager,
+			Force:        true,
+		})
+		if err != nil {
+			return fmt.Errorf("fail to apply %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Disable removes the addons first so the manager can run their cleanup, then the manifests.
+// like disabling the MCE component, the CRD and the namespace are left behind with the data stored in them
+func (i *UpstreamInstaller) Disable(hubClient dynamic.Interface) error {
+	addons, err := listManagedServiceAccountAddons(hubClient)
+	if err != nil {
+		return err
+	}
+	for cluster := range addons {
+		err := hubClient.Resource(gvrManagedClusterAddon).Namespace(cluster).
+			Delete(context.TODO(), "managed-serviceaccount", metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	deadline := time.Now().Add(UpstreamAddonRemovalTimeout)
+	for len(addons) > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("managed-serviceaccount addons are still present after %v: %d left",
+				UpstreamAddonRemovalTimeout, len(addons))
+		}
+		time.Sleep(WaitPollInterval)
+		if addons, err = listManagedServiceAccountAddons(hubClient); err != nil {
+			return err
+		}
+	}
+
+	objs, err := i.manifests()
+	if err != nil {
+		return err
+	}
+	for idx := len(objs) - 1; idx >= 0; idx-- {
+		obj := objs[idx]
+		if kind := obj.GetKind(); kind == "CustomResourceDefinition" || kind == "Namespace" {
+			continue
+		}
+		resource, err := upstreamResource(hubClient, obj)
+		if err != nil {
+			return err
+		}
+		err = resource.Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("fail to delete %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// IsEnabled is true when both the ClusterManagementAddOn and the addon manager exist
+func (i *UpstreamInstaller) IsEnabled(hubClient dynamic.Interface) (bool, error) {
+	_, err := GetManagedServiceAccountClusterManagementAddon(hubClient)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = hubClient.Resource(upstreamManifestResources["Deployment"]).
+		Namespace(UpstreamInstallNamespace).
+		Get(context.TODO(), UpstreamManagerDeploymentName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// manifests decodes the embedded manifests and sets the image on the addon manager
+func (i *UpstreamInstaller) manifests() ([]*unstructured.Unstructured, error) {
+	entries, err := upstreamManifests.ReadDir("manifests/upstream")
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for _, entry := range entries {
+		data, err := upstreamManifests.ReadFile(path.Join("manifests/upstream", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		jsonData, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("fail to decode %s: %v", entry.Name(), err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(jsonData); err != nil {
+			return nil, fmt.Errorf("fail to decode %s: %v", entry.Name(), err)
+		}
+
+		if obj.GetKind() == "Deployment" && i.Image != "" {
+			if err := setManagerImage(obj, i.Image); err != nil {
+				return nil, err
+			}
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// setManagerImage points the manager container and the agents it deploys at the image
+func setManagerImage(deployment *unstructured.Unstructured, image string) error {
+	containers, _, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected format for container %v expecting it to be a map[string]interface{}", c)
+		}
+		container["image"] = image
+
+		args, found, err := unstructured.NestedStringSlice(container, "args")
+		if err != nil || !found {
+			continue
+		}
+		for idx := range args {
+			if strings.HasPrefix(args[idx], "--agent-image-name=") {
+				args[idx] = "--agent-image-name=" + image
+			}
+		}
+		if err := unstructured.SetNestedStringSlice(container, args, "args"); err != nil {
+			return err
+		}
+	}
+	return unstructured.SetNestedSlice(deployment.Object, containers, "spec", "template", "spec", "containers")
+}
+
+func upstreamResource(hubClient dynamic.Interface, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvr, ok := upstreamManifestResources[obj.GetKind()]
+	if !ok {
+		return nil, fmt.Errorf("no resource known for kind %s of %s", obj.GetKind(), obj.GetName())
+	}
+	if obj.GetNamespace() != "" {
+		return hubClient.Resource(gvr).Namespace(obj.GetNamespace()), nil
+	}
+	return hubClient.Resource(gvr), nil
+}
@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	msav1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
+)
+
+// WaitPollInterval is how often the wait helpers fall back to polling,
+// when the watch cannot be opened or stays quiet
+var WaitPollInterval = time.Second * 10
+
+// WaitError is returned by the wait helpers when the context ends before the object is ready,
+// it carries what was last seen so the failure explains itself
+type WaitError struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// condition types expected to be True
+	Expected []string
+	// conditions of the object the last time it was read, empty if it was never read
+	LastConditions []metav1.Condition
+	// readable report of the object the last time it was read, for kinds that have one
+	Report string
+	// last error returned while reading or watching the object
+	LastError error
+	// context error that ended the wait
+	Cause error
+}
+
+func (e *WaitError) Error() string {
+	msg := fmt.Sprintf("%v waiting for %s %s/%s to have %s",
+		e.Cause, e.Kind, e.Namespace, e.Name, strings.Join(e.Expected, ","))
+	switch {
+	case e.Report != "":
+		// the report already lists the conditions
+		msg += "\n" + e.Report
+	case len(e.LastConditions) == 0:
+		msg += ", no condition seen"
+	default:
+		for _, condition := range e.LastConditions {
+			msg += fmt.Sprintf("\n  %s=%s reason=%s message=%q",
+				condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+	if e.LastError != nil {
+		msg += fmt.Sprintf("\n  last error: %v", e.LastError)
+	}
+	return msg
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Cause
+}
+
+func conditionsFromUnstructured(u *unstructured.Unstructured) ([]metav1.Condition, error) {
+	uConditions, _, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []metav1.Condition{}
+	for _, uCondition := range uConditions {
+		m, ok := uCondition.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected format for condition %v", uCondition)
+		}
+		condition := metav1.Condition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &condition); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func hasTrueConditions(conditions []metav1.Condition, expected []string) bool {
+	for _, conditionType := range expected {
+		found := false
+		for _, condition := range conditions {
+			if condition.Type == conditionType && condition.Status == metav1.ConditionTrue {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForConditions returns the object once every expected condition is True. It watches the
+// object and reads it again every WaitPollInterval, so it still works when watches are unavailable.
+// report, when not nil, renders the last seen object into the WaitError.
+func waitForConditions(
+	ctx context.Context,
+	resource dynamic.ResourceInterface,
+	kind string,
+	namespace string,
+	name string,
+	expected []string,
+	report func(*unstructured.Unstructured) string,
+) (*unstructured.Unstructured, error) {
+	waitErr := &WaitError{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Expected:  expected,
+	}
+
+	// check returns true when the object is ready, and records what it saw otherwise
+	check := func(u *unstructured.Unstructured) bool {
+		conditions, err := conditionsFromUnstructured(u)
+		if err != nil {
+			waitErr.LastError = err
+			return false
+		}
+		waitErr.LastConditions = conditions
+		if report != nil {
+			waitErr.Report = report(u)
+		}
+		return hasTrueConditions(conditions, expected)
+	}
+
+	for {
+		u, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err == nil && check(u) {
+			return u, nil
+		}
+		if err != nil && ctx.Err() == nil {
+			waitErr.LastError = err
+		}
+
+		listOptions := metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+		}
+		if u != nil {
+			listOptions.ResourceVersion = u.GetResourceVersion()
+		}
+
+		watcher, err := resource.Watch(ctx, listOptions)
+		if err != nil {
+			if ctx.Err() == nil {
+				waitErr.LastError = err
+			}
+			watcher = watch.NewEmptyWatch()
+		}
+
+		ready, err := waitForWatchEvent(ctx, watcher, check)
+		watcher.Stop()
+		if ready != nil {
+			return ready, nil
+		}
+		if err != nil {
+			waitErr.Cause = err
+			return nil, waitErr
+		}
+	}
+}
+
+// waitForWatchEvent consumes events until the object is ready, the watch ends or the poll interval elapses,
+// it only returns an error when the context is done
+func waitForWatchEvent(
+	ctx context.Context,
+	watcher watch.Interface,
+	check func(*unstructured.Unstructured) bool,
+) (*unstructured.Unstructured, error) {
+	poll := time.NewTimer(WaitPollInterval)
+	defer poll.Stop()
+
+	events := watcher.ResultChan()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-poll.C:
+			return nil, nil
+		case event, ok := <-events:
+			if !ok {
+				// the watch ended, wait for the next poll before reading again
+				events = nil
+				continue
+			}
+			u, isUnstructured := event.Object.(*unstructured.Unstructured)
+			if !isUnstructured || event.Type == watch.Deleted || event.Type == watch.Error {
+				continue
+			}
+			if check(u) {
+				return u, nil
+			}
+		}
+	}
+}
+
+// WaitForManagedServiceAccountReady waits for the SecretCreated and TokenReported conditions,
+// the returned error is a *WaitError with the last seen conditions when the context ends first
+func WaitForManagedServiceAccountReady(
+	ctx context.Context,
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	name string,
+) (*msav1beta1.ManagedServiceAccount, error) {
+	resource := hubClient.Resource(managedServiceAccountGVR(managedServiceAccountVersion)).Namespace(managedCluster.Name)
+
+	u, err := waitForConditions(ctx, resource, "ManagedServiceAccount", managedCluster.Name, name, []string{
+		msav1beta1.ConditionTypeSecretCreated,
+		msav1beta1.ConditionTypeTokenReported,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManagedServiceAccount(u)
+}
+
+// WaitForAddonAvailable waits for the managed-serviceaccount ManagedClusterAddOn to be Available,
+// the returned error is a *WaitError with the last seen health report when the context ends first
+func WaitForAddonAvailable(
+	ctx context.Context,
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+) (*addonv1alpha1.ManagedClusterAddOn, error) {
+	return WaitForAddonConditions(ctx, hubClient, managedCluster, addonv1alpha1.ManagedClusterAddOnConditionAvailable)
+}
+
+// WaitForAddonConditions waits for every condition type to be True on the managed-serviceaccount ManagedClusterAddOn
+func WaitForAddonConditions(
+	ctx context.Context,
+	hubClient dynamic.Interface,
+	managedCluster *clusterv1.ManagedCluster,
+	conditionTypes ...string,
+) (*addonv1alpha1.ManagedClusterAddOn, error) {
+	resource := hubClient.Resource(gvrManagedClusterAddon).Namespace(managedCluster.Name)
+
+	u, err := waitForConditions(ctx, resource, "ManagedClusterAddOn", managedCluster.Name, "managed-serviceaccount",
+		conditionTypes, addonHealthReport)
+	if err != nil {
+		return nil, err
+	}
+
+	return unstructuredToManagedClusterAddon(u)
+}